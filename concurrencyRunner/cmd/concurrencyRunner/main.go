@@ -1,11 +1,14 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	crConfig "github.com/weinberg/concurrencyRunner/pkg/config"
 	"github.com/weinberg/concurrencyRunner/pkg/runner"
 	"os"
+	"os/signal"
+	"syscall"
 )
 
 func main() {
@@ -23,11 +26,26 @@ func main() {
 		os.Exit(1)
 	}
 
-	err = runner.Run(config)
+	// cancel the run on SIGINT or SIGTERM so the instances are cleaned up. A
+	// second signal exits immediately.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-ctx.Done()
+		stop()
+		fmt.Printf("Interrupted, cleaning up\n")
+	}()
+
+	verdict, err := runner.Run(ctx, config)
 	if err != nil {
 		fmt.Printf("Error: %s\n", err)
 		os.Exit(1)
 	}
 
+	verdict.Print()
+	if !verdict.Passed() {
+		// distinguish invariant violations from errors running the scenario
+		os.Exit(2)
+	}
+
 	os.Exit(0)
 }
@@ -2,10 +2,12 @@ module github.com/weinberg/concurrencyRunner
 
 go 1.18
 
-require github.com/google/go-dap v0.6.0
+require (
+	github.com/google/go-dap v0.6.0
+	github.com/gookit/color v1.5.0
+)
 
 require (
-	github.com/gookit/color v1.5.0 // indirect
 	github.com/xo/terminfo v0.0.0-20210125001918-ca9a967f8778 // indirect
 	golang.org/x/sys v0.0.0-20210330210617-4fbd30eecc44 // indirect
 )
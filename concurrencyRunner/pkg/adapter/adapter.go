@@ -0,0 +1,148 @@
+package adapter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/weinberg/concurrencyRunner/pkg/client"
+	"github.com/weinberg/concurrencyRunner/pkg/config"
+	"io"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Adapter launches and manages the debug adapter for a single instance.
+// Implementations register a Factory under the name used for the `adapter`
+// field in the config file.
+type Adapter interface {
+	// Launch starts the debug adapter and returns a client connected to it
+	Launch(ctx context.Context, instance config.Instance) (*client.Client, error)
+	// LaunchArgs returns the arguments of the DAP launch request for the
+	// instance
+	LaunchArgs(instance config.Instance) (map[string]interface{}, error)
+	// IsChatter reports whether a line of adapter output was written by the
+	// adapter itself rather than the debuggee
+	IsChatter(line string) bool
+	// Output returns the output of the adapter process, or nil if there is
+	// none
+	Output() io.Reader
+	// Shutdown stops the adapter. It is called after the debug session has
+	// been disconnected and the client closed, so adapters which exit by
+	// themselves are given a grace period before they are killed.
+	Shutdown() error
+}
+
+// Attacher is implemented by adapters which can attach to a running process
+type Attacher interface {
+	// AttachArgs returns the arguments of the DAP attach request for the
+	// instance
+	AttachArgs(instance config.Instance) (map[string]interface{}, error)
+}
+
+// LazyBreakpoints is implemented by adapters which only verify breakpoints
+// once the source has been loaded by the debuggee. Breakpoints which are
+// unverified when set are not an error for these adapters.
+type LazyBreakpoints interface {
+	LazyBreakpoints() bool
+}
+
+// Factory creates a new Adapter
+type Factory func() Adapter
+
+var (
+	registryMu sync.Mutex
+	registry   = map[string]Factory{}
+)
+
+// Register makes an adapter available under name. It panics if an adapter is
+// already registered with that name.
+func Register(name string, factory Factory) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if _, ok := registry[name]; ok {
+		panic(fmt.Sprintf("adapter '%s' registered twice", name))
+	}
+	registry[name] = factory
+}
+
+// New creates the adapter registered under name
+func New(name string) (Adapter, error) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	factory, ok := registry[name]
+	if !ok {
+		return nil, unknownAdapter(name)
+	}
+	return factory(), nil
+}
+
+// Validate returns an error if no adapter is registered under name
+func Validate(name string) error {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if _, ok := registry[name]; !ok {
+		return unknownAdapter(name)
+	}
+	return nil
+}
+
+func unknownAdapter(name string) error {
+	return fmt.Errorf("unknown adapter '%s', available adapters: %s", name, strings.Join(names(), ", "))
+}
+
+// names returns the sorted names of the registered adapters
+func names() []string {
+	var result []string
+	for name := range registry {
+		result = append(result, name)
+	}
+	sort.Strings(result)
+	return result
+}
+
+// ResolvePid returns the pid of the process an instance attaches to, either
+// its Pid or the single process whose name exactly matches ProcessName
+func ResolvePid(instance config.Instance) (int, error) {
+	if instance.Pid != 0 {
+		return instance.Pid, nil
+	}
+	if instance.ProcessName == "" {
+		return 0, fmt.Errorf("instance '%s' attaches but has neither pid nor processName", instance.Name)
+	}
+
+	output, err := exec.Command("pgrep", "-x", instance.ProcessName).Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 {
+			return 0, fmt.Errorf("no process named '%s'", instance.ProcessName)
+		}
+		return 0, fmt.Errorf("cannot find process '%s': %w", instance.ProcessName, err)
+	}
+
+	pids := strings.Fields(string(output))
+	if len(pids) != 1 {
+		return 0, fmt.Errorf("%d processes named '%s' (%s), use pid instead",
+			len(pids), instance.ProcessName, strings.Join(pids, ", "))
+	}
+	return strconv.Atoi(pids[0])
+}
+
+// ParseEnv parses an instance env string of the form "KEY=value;KEY2=value2"
+func ParseEnv(env string) (map[string]string, error) {
+	envVars := make(map[string]string)
+	if env == "" {
+		return envVars, nil
+	}
+	envKvs := strings.Split(env, ";")
+	for _, envKv := range envKvs {
+		kv := strings.SplitN(envKv, "=", 2)
+		if len(kv) != 2 || kv[0] == "" || kv[1] == "" {
+			return nil, fmt.Errorf("invalid env string: %s", env)
+		}
+		envVars[kv[0]] = kv[1]
+	}
+	return envVars, nil
+}
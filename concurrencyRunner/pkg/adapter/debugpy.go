@@ -0,0 +1,75 @@
+package adapter
+
+import (
+	"context"
+	"github.com/weinberg/concurrencyRunner/pkg/client"
+	"github.com/weinberg/concurrencyRunner/pkg/config"
+	"io"
+)
+
+func init() {
+	Register("debugpy", NewDebugpy)
+}
+
+// Debugpy runs `python -m debugpy.adapter` for a Python instance. The adapter
+// speaks DAP over its stdin and stdout.
+type Debugpy struct {
+	Url  string
+	proc *process
+}
+
+func NewDebugpy() Adapter {
+	return &Debugpy{}
+}
+
+func (d *Debugpy) Launch(ctx context.Context, instance config.Instance) (cl *client.Client, err error) {
+	// connect to a debugpy adapter which is already listening
+	if instance.Address != "" {
+		d.Url = instance.Address
+		return connect(d.Url, nil)
+	}
+
+	python := "python"
+	if instance.AdapterPath != "" {
+		python = instance.AdapterPath
+	}
+
+	d.proc, cl, err = startStdioProcess("debugpy", python, "-m", "debugpy.adapter")
+	return cl, err
+}
+
+func (d *Debugpy) LaunchArgs(instance config.Instance) (map[string]interface{}, error) {
+	envVars, err := ParseEnv(instance.Env)
+	if err != nil {
+		return nil, err
+	}
+
+	args := map[string]interface{}{
+		"request":     "launch",
+		"type":        "python",
+		"program":     instance.Program,
+		"stopOnEntry": true,
+		"env":         envVars,
+		"args":        instance.Args,
+		// program output is sent to the client as DAP output events rather
+		// than written to a terminal
+		"console":    "internalConsole",
+		"justMyCode": false,
+	}
+	if instance.Cwd != "" {
+		args["cwd"] = instance.Cwd
+	}
+	return args, nil
+}
+
+func (d *Debugpy) IsChatter(line string) bool {
+	return false
+}
+
+func (d *Debugpy) Output() io.Reader {
+	return d.proc.Output()
+}
+
+func (d *Debugpy) Shutdown() error {
+	return d.proc.Stop(SHUTDOWN_GRACE)
+}
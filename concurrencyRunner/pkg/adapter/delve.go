@@ -0,0 +1,107 @@
+package adapter
+
+import (
+	"context"
+	"github.com/weinberg/concurrencyRunner/pkg/client"
+	"github.com/weinberg/concurrencyRunner/pkg/config"
+	"io"
+	"regexp"
+	"strings"
+)
+
+func init() {
+	Register("delve", NewDelve)
+}
+
+// delveListening matches the line dlv prints once it is listening
+var delveListening = regexp.MustCompile(`^DAP server listening at: (\S+)`)
+
+// Delve runs `dlv dap` for a Go instance
+type Delve struct {
+	Url  string
+	proc *process
+}
+
+func NewDelve() Adapter {
+	return &Delve{}
+}
+
+func (d *Delve) Launch(ctx context.Context, instance config.Instance) (cl *client.Client, err error) {
+	// connect to a DAP server which is already running, e.g. a headless dlv
+	if instance.Address != "" {
+		d.Url = instance.Address
+		return connect(d.Url, nil)
+	}
+
+	dlv := "dlv"
+	if instance.AdapterPath != "" {
+		dlv = instance.AdapterPath
+	}
+
+	// let the OS choose the port, dlv reports it once it is listening
+	d.proc, err = startProcess("delve", delveListening, dlv, "dap", "--listen", hostname+":0")
+	if err != nil {
+		return nil, err
+	}
+	d.Url, err = d.proc.waitForAddress()
+	if err != nil {
+		return nil, err
+	}
+
+	return connect(d.Url, d.proc)
+}
+
+func (d *Delve) LaunchArgs(instance config.Instance) (map[string]interface{}, error) {
+	envVars, err := ParseEnv(instance.Env)
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]interface{}{
+		"request":     "launch",
+		"mode":        "debug",
+		"program":     instance.Program,
+		"stopOnEntry": true,
+		"env":         envVars,
+		"dlvCwd":      instance.Cwd,
+		"args":        instance.Args,
+		// send program output to the client as output events
+		"outputMode": "remote",
+	}, nil
+}
+
+// AttachArgs attaches to a local process with delve's `local` attach mode, or
+// to the process of a headless dlv server at the instance's Address with the
+// `remote` attach mode
+func (d *Delve) AttachArgs(instance config.Instance) (map[string]interface{}, error) {
+	if instance.Address != "" {
+		return map[string]interface{}{
+			"request":     "attach",
+			"mode":        "remote",
+			"stopOnEntry": true,
+		}, nil
+	}
+
+	pid, err := ResolvePid(instance)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{
+		"request":     "attach",
+		"mode":        "local",
+		"processId":   pid,
+		"stopOnEntry": true,
+	}, nil
+}
+
+func (d *Delve) IsChatter(line string) bool {
+	return strings.HasPrefix(line, "DAP server")
+}
+
+func (d *Delve) Output() io.Reader {
+	return d.proc.Output()
+}
+
+func (d *Delve) Shutdown() error {
+	return d.proc.Stop(SHUTDOWN_GRACE)
+}
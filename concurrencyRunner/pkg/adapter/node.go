@@ -0,0 +1,213 @@
+package adapter
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/weinberg/concurrencyRunner/pkg/client"
+	"github.com/weinberg/concurrencyRunner/pkg/config"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+)
+
+func init() {
+	Register("node", NewNode)
+}
+
+// nodeListening matches the line js-debug prints once it is listening
+var nodeListening = regexp.MustCompile(`^Debug server listening at (\S+)`)
+
+// NODE_CHILD_SESSION_TIMEOUT is how long to wait for js-debug to ask for the
+// child session of the launched program
+const NODE_CHILD_SESSION_TIMEOUT = 30 * time.Second
+
+// Node runs js-debug's standalone DAP server for a Node.js instance.
+//
+// js-debug launches the program in a parent session and then asks the client
+// to start a child session, with a `startDebugging` reverse request, for the
+// program itself. Launch performs the parent session and returns a client for
+// the child session, which is where breakpoints are set and the program is
+// controlled.
+type Node struct {
+	Url  string
+	proc *process
+	// parent is the client of the parent session
+	parent *client.Client
+	// childConfiguration are the launch args of the child session, received
+	// in the startDebugging request
+	childConfiguration map[string]interface{}
+}
+
+func NewNode() Adapter {
+	return &Node{}
+}
+
+func (n *Node) Launch(ctx context.Context, instance config.Instance) (*client.Client, error) {
+	if instance.Address != "" {
+		// connect to a js-debug server which is already listening
+		n.Url = instance.Address
+	} else {
+		err := n.startServer(instance)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err := n.launchParent(ctx, instance)
+	if err != nil {
+		return nil, err
+	}
+
+	return connect(n.Url, n.proc)
+}
+
+// startServer runs js-debug's DAP server on a port chosen by the OS
+func (n *Node) startServer(instance config.Instance) (err error) {
+	if instance.AdapterPath == "" {
+		return fmt.Errorf("adapter 'node' requires adapterPath to be the path of js-debug's dapDebugServer.js")
+	}
+
+	n.proc, err = startProcess("js-debug", nodeListening, "node", instance.AdapterPath, "0", hostname)
+	if err != nil {
+		return err
+	}
+	n.Url, err = n.proc.waitForAddress()
+	return err
+}
+
+// launchParent runs the parent session until js-debug requests the child
+// session
+func (n *Node) launchParent(ctx context.Context, instance config.Instance) (err error) {
+	n.parent, err = connect(n.Url, n.proc)
+	if err != nil {
+		return err
+	}
+	// js-debug starts the child session with a startDebugging request
+	n.parent.HandleReverseRequests()
+
+	_, err = n.parent.InitializeWithArgs(ctx, map[string]interface{}{
+		"clientID":                      "concurrency-lab",
+		"adapterID":                     "pwa-node",
+		"pathFormat":                    "path",
+		"linesStartAt1":                 true,
+		"columnsStartAt1":               true,
+		"supportsVariableType":          true,
+		"supportsStartDebuggingRequest": true,
+		"locale":                        "en-us",
+	})
+	if err != nil {
+		return err
+	}
+
+	launchArgs, err := n.parentLaunchArgs(instance)
+	if err != nil {
+		return err
+	}
+	// the launch response is not needed, js-debug reports problems through
+	// the child session
+	initialized := n.parent.Subscribe(client.EventTypes("initialized"))
+	defer initialized.Unsubscribe()
+	_, err = n.parent.LaunchRequestWithArgs(launchArgs)
+	if err != nil {
+		return err
+	}
+	_, err = initialized.Next(ctx)
+	if err != nil {
+		return err
+	}
+
+	_, err = n.parent.ConfigurationDone(ctx)
+	if err != nil {
+		return err
+	}
+
+	request, err := n.parent.ReadReverseRequestWithTimeout(ctx, "startDebugging", NODE_CHILD_SESSION_TIMEOUT)
+	if err != nil {
+		return fmt.Errorf("js-debug did not start a child session: %w", err)
+	}
+
+	var arguments struct {
+		Configuration map[string]interface{} `json:"configuration"`
+	}
+	err = json.Unmarshal(request.Arguments, &arguments)
+	if err != nil {
+		return fmt.Errorf("invalid startDebugging request: %w", err)
+	}
+	n.childConfiguration = arguments.Configuration
+
+	err = n.parent.RespondToReverseRequest(request, true, "")
+	if err != nil {
+		return err
+	}
+
+	// the parent session is not used again but its messages must be
+	// consumed so the connection does not stall
+	go drain(n.parent)
+
+	return nil
+}
+
+func (n *Node) parentLaunchArgs(instance config.Instance) (map[string]interface{}, error) {
+	envVars, err := ParseEnv(instance.Env)
+	if err != nil {
+		return nil, err
+	}
+
+	args := map[string]interface{}{
+		"type":        "pwa-node",
+		"request":     "launch",
+		"name":        instance.Name,
+		"program":     instance.Program,
+		"args":        instance.Args,
+		"env":         envVars,
+		"stopOnEntry": true,
+		// program output is sent as output events rather than to a terminal
+		"console":       "internalConsole",
+		"outputCapture": "std",
+	}
+	if instance.Cwd != "" {
+		args["cwd"] = instance.Cwd
+	}
+	return args, nil
+}
+
+// LaunchArgs returns the configuration js-debug sent for the child session
+func (n *Node) LaunchArgs(instance config.Instance) (map[string]interface{}, error) {
+	if n.childConfiguration == nil {
+		return nil, fmt.Errorf("node adapter has not been launched")
+	}
+	return n.childConfiguration, nil
+}
+
+func (n *Node) IsChatter(line string) bool {
+	return strings.HasPrefix(line, "Debug server listening at")
+}
+
+// LazyBreakpoints is true since js-debug only verifies breakpoints once the
+// script has been parsed
+func (n *Node) LazyBreakpoints() bool {
+	return true
+}
+
+func (n *Node) Output() io.Reader {
+	return n.proc.Output()
+}
+
+// Shutdown kills the js-debug server at once since, unlike other adapters, it
+// keeps running after its sessions end
+func (n *Node) Shutdown() error {
+	if n.parent != nil {
+		n.parent.Close()
+	}
+	return n.proc.Kill()
+}
+
+// drain discards all messages received by cl until its connection closes
+func drain(cl *client.Client) {
+	cl.DropUnmatchedEvents()
+	for request := range cl.Requests {
+		cl.RespondToReverseRequest(request, false, "not supported")
+	}
+}
@@ -0,0 +1,277 @@
+package adapter
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/weinberg/concurrencyRunner/pkg/client"
+	"io"
+	"os/exec"
+	"regexp"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// START_TIMEOUT is how long an adapter process has to start listening
+const START_TIMEOUT = 10 * time.Second
+
+// RECENT_LINES is the number of output lines reported when a process fails
+const RECENT_LINES = 20
+
+// SHUTDOWN_GRACE is how long an adapter process has to exit after its debug
+// session is disconnected before it is killed
+const SHUTDOWN_GRACE = 5 * time.Second
+
+const hostname = "127.0.0.1"
+
+// process is a running adapter process. Its output, the combined stdout and
+// stderr or only stderr if it speaks DAP over stdio, is scanned line by line
+// for the address it is listening on and forwarded to output. Lines are
+// queued until output is read so that a process which exits early is noticed
+// even when nothing is reading its output yet.
+type process struct {
+	name string
+	cmd  *exec.Cmd
+	// listening is matched against each output line, the first submatch is
+	// the address
+	listening *regexp.Regexp
+	addresses chan string
+	output    *io.PipeReader
+	exited    chan struct{}
+	exitErr   error
+	// readers are closed once the other readers of the process's pipes, such
+	// as the DAP client of a stdio adapter, have read everything. Wait closes
+	// the pipes so it is only called after that.
+	readers []<-chan struct{}
+
+	mu      sync.Mutex
+	recent  []string
+	pending []string
+	done    bool
+	notify  chan struct{}
+}
+
+func newProcess(name string, listening *regexp.Regexp, command string, args ...string) *process {
+	cmd := exec.Command(command, args...)
+	// a process group of its own keeps a Ctrl-C in the terminal from reaching
+	// the adapter and its debuggee, the runner shuts them down instead
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	return &process{
+		name:      name,
+		cmd:       cmd,
+		listening: listening,
+		addresses: make(chan string, 1),
+		exited:    make(chan struct{}),
+		notify:    make(chan struct{}, 1),
+	}
+}
+
+// startProcess runs an adapter process. If listening is not nil it is used to
+// find the address the process is listening on in its output.
+func startProcess(name string, listening *regexp.Regexp, command string, args ...string) (*process, error) {
+	p := newProcess(name, listening, command, args...)
+
+	stdout, err := p.cmd.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("cannot start %s: %w", name, err)
+	}
+	p.cmd.Stderr = p.cmd.Stdout
+	err = p.cmd.Start()
+	if err != nil {
+		return nil, fmt.Errorf("cannot start %s: %w", name, err)
+	}
+
+	p.watch(stdout)
+	return p, nil
+}
+
+// startStdioProcess runs an adapter process which speaks DAP over its stdin
+// and stdout and returns a client connected to it. The output of the process
+// is its stderr.
+func startStdioProcess(name string, command string, args ...string) (*process, *client.Client, error) {
+	p := newProcess(name, nil, command, args...)
+
+	stderr, err := p.cmd.StderrPipe()
+	if err != nil {
+		return nil, nil, fmt.Errorf("cannot start %s: %w", name, err)
+	}
+	cl, err := client.NewStdioClient(p.cmd)
+	if err != nil {
+		return nil, nil, fmt.Errorf("cannot start %s: %w", name, err)
+	}
+
+	p.readers = append(p.readers, cl.Done())
+	p.watch(stderr)
+	return p, cl, nil
+}
+
+// watch forwards output until the process exits
+func (p *process) watch(output io.Reader) {
+	outputReader, outputWriter := io.Pipe()
+	p.output = outputReader
+	go p.scan(output)
+	go p.forward(outputWriter)
+}
+
+// scan reads the process output until it exits
+func (p *process) scan(stdout io.Reader) {
+	scanner := bufio.NewScanner(stdout)
+	found := false
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		if !found && p.listening != nil {
+			if match := p.listening.FindStringSubmatch(line); match != nil {
+				found = true
+				p.addresses <- match[1]
+			}
+		}
+
+		p.mu.Lock()
+		p.recent = append(p.recent, line)
+		if len(p.recent) > RECENT_LINES {
+			p.recent = p.recent[1:]
+		}
+		p.pending = append(p.pending, line)
+		p.mu.Unlock()
+		p.wake()
+	}
+
+	// all output has been read so the process has exited, or is about to
+	for _, done := range p.readers {
+		<-done
+	}
+	p.exitErr = p.cmd.Wait()
+	close(p.exited)
+
+	p.mu.Lock()
+	p.done = true
+	p.mu.Unlock()
+	p.wake()
+}
+
+func (p *process) wake() {
+	select {
+	case p.notify <- struct{}{}:
+	default:
+	}
+}
+
+// forward writes queued output lines to output
+func (p *process) forward(output *io.PipeWriter) {
+	for {
+		p.mu.Lock()
+		lines := p.pending
+		p.pending = nil
+		done := p.done
+		p.mu.Unlock()
+
+		for _, line := range lines {
+			_, err := output.Write([]byte(line + "\n"))
+			if err != nil {
+				return
+			}
+		}
+
+		if len(lines) == 0 {
+			if done {
+				output.Close()
+				return
+			}
+			<-p.notify
+		}
+	}
+}
+
+// waitForAddress returns the address found in the output of the process
+func (p *process) waitForAddress() (string, error) {
+	timer := time.NewTimer(START_TIMEOUT)
+	defer timer.Stop()
+	select {
+	case address := <-p.addresses:
+		return address, nil
+	case <-p.exited:
+		return "", p.exitError()
+	case <-timer.C:
+		return "", fmt.Errorf("%s did not start listening within %s%s", p.name, START_TIMEOUT, p.recentOutput())
+	}
+}
+
+// exitError describes the process exiting unexpectedly
+func (p *process) exitError() error {
+	status := "exited"
+	if p.exitErr != nil {
+		status = p.exitErr.Error()
+	}
+	return fmt.Errorf("%s failed to start (%s)%s", p.name, status, p.recentOutput())
+}
+
+func (p *process) recentOutput() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.recent) == 0 {
+		return ""
+	}
+	return ", output:\n" + strings.Join(p.recent, "\n")
+}
+
+// Output returns the output of the process, or nil if there is no process
+func (p *process) Output() io.Reader {
+	if p == nil {
+		return nil
+	}
+	return p.output
+}
+
+// Stop waits up to grace for the process to exit by itself, as adapters do
+// once their debug session is disconnected, and kills it otherwise
+func (p *process) Stop(grace time.Duration) error {
+	if p == nil {
+		return nil
+	}
+	select {
+	case <-p.exited:
+		return nil
+	case <-time.After(grace):
+	}
+	return p.Kill()
+}
+
+// Kill kills the process if it has not already exited
+func (p *process) Kill() error {
+	if p == nil {
+		return nil
+	}
+	select {
+	case <-p.exited:
+		return nil
+	default:
+	}
+	return p.cmd.Process.Kill()
+}
+
+// connect connects a client to url, retrying until the adapter accepts the
+// connection. If p is not nil connecting fails as soon as the process exits.
+func connect(url string, p *process) (cl *client.Client, err error) {
+	deadline := time.Now().Add(START_TIMEOUT)
+	for {
+		cl, err = client.NewClient(url)
+		if err == nil {
+			return cl, nil
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("cannot connect to debug adapter at %s: %w", url, err)
+		}
+
+		var exited <-chan struct{}
+		if p != nil {
+			exited = p.exited
+		}
+		select {
+		case <-exited:
+			return nil, p.exitError()
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+}
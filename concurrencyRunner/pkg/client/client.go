@@ -2,14 +2,27 @@ package client
 
 import (
 	"bufio"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/google/go-dap"
+	"io"
 	"net"
+	"os"
+	"os/exec"
 	"path/filepath"
 	"reflect"
+	"sync"
+	"time"
 )
 
+// ErrTimeout is returned when a message is not received before a deadline
+var ErrTimeout = errors.New("timed out waiting for debug adapter")
+
+// ErrClosed is returned when the connection to the debug adapter is closed
+var ErrClosed = errors.New("debug adapter connection closed")
+
 // This client code is from the Delve test suite
 // @see https://github.com/go-delve/delve/blob/v1.8.2/service/dap/daptest/client.go#L256
 
@@ -17,14 +30,39 @@ import (
 // It does not (yet?) implement service.Client interface.
 // All client methods are synchronous.
 type Client struct {
-	conn   net.Conn
+	conn   io.ReadWriteCloser
 	reader *bufio.Reader
 	// seq is used to track the sequence number of each
 	// requests that the client sends to the readModifyWrite
 	seq                int
-	initializeResponse dap.Message
-	Events             chan dap.Message
-	Responses          chan dap.Message
+	initializeResponse *dap.InitializeResponse
+	// Requests receives reverse requests from the debug adapter once
+	// HandleReverseRequests has been called. Until then, or while it is full,
+	// requests are answered with an error.
+	Requests chan *ReverseRequest
+	// handleRequests is set by HandleReverseRequests
+	requestsMu     sync.Mutex
+	handleRequests bool
+
+	// seqMu guards seq, sendMu serializes writes to conn. Requests and
+	// responses to reverse requests may be sent from different goroutines.
+	seqMu  sync.Mutex
+	sendMu sync.Mutex
+
+	// pending maps the seq of each request awaiting a response to its future
+	pendingMu sync.Mutex
+	pending   map[int]*Future
+
+	// subscriptions receive the events matching their filters, events holds
+	// the events which match none unless dropUnmatched is set
+	subscriptionsMu sync.Mutex
+	subscriptions   []*Subscription
+	events          *Subscription
+	dropUnmatched   bool
+
+	// done is closed when the read loop stops. readErr is why it stopped.
+	done    chan struct{}
+	readErr error
 }
 
 // NewClient creates a new Client over a TCP connection.
@@ -42,9 +80,39 @@ func NewClient(addr string) (cl *Client, err error) {
 	return
 }
 
+// ReadMessageLoop dispatches messages from the debug adapter until the
+// connection is closed, then closes the Requests channel and Done
 func ReadMessageLoop(cl *Client) {
+	defer cl.stop()
 	for {
-		message, err := cl.ReadMessage()
+		content, err := dap.ReadBaseMessage(cl.reader)
+		if err != nil {
+			if !errors.Is(err, io.EOF) && !errors.Is(err, net.ErrClosed) && !errors.Is(err, os.ErrClosed) {
+				fmt.Printf("Error reading from debug adapter: %v\n", err)
+			}
+			cl.readErr = err
+			return
+		}
+
+		// requests are decoded separately since go-dap cannot decode newer
+		// reverse requests such as 'startDebugging'
+		if request, ok := decodeReverseRequest(content); ok {
+			cl.requestsMu.Lock()
+			handled := cl.handleRequests
+			cl.requestsMu.Unlock()
+			if handled {
+				select {
+				case cl.Requests <- request:
+					continue
+				default:
+				}
+			}
+			// the adapter must not wait for a response which never comes
+			_ = cl.RespondToReverseRequest(request, false, "request not handled")
+			continue
+		}
+
+		message, err := dap.DecodeProtocolMessage(content)
 		if err != nil {
 			fmt.Printf("Error reading from debug adapter: %v\n", err)
 		} else {
@@ -52,76 +120,181 @@ func ReadMessageLoop(cl *Client) {
 
 			if messageType.Implements(reflect.TypeOf((*dap.ResponseMessage)(nil)).Elem()) {
 				// fmt.Printf("Response: %s\n", reflect.TypeOf(message))
-				cl.Responses <- message
+				cl.resolve(message.(dap.ResponseMessage))
 			} else if messageType.Implements(reflect.TypeOf((*dap.EventMessage)(nil)).Elem()) {
 				// fmt.Printf("Event   : %s\n", reflect.TypeOf(message))
 				// fmt.Printf("OUTPUT: %v+", message)
-				cl.Events <- message
-			} else if messageType.Implements(reflect.TypeOf((*dap.RequestMessage)(nil)).Elem()) {
-				// fmt.Printf("Request : %s\n", reflect.TypeOf(message))
-				// todo
+				cl.dispatch(message.(dap.EventMessage))
 			}
 		}
 	}
 }
 
+// HandleReverseRequests delivers reverse requests to Requests from now on.
+// The caller must read Requests and respond to every request.
+func (c *Client) HandleReverseRequests() {
+	c.requestsMu.Lock()
+	c.handleRequests = true
+	c.requestsMu.Unlock()
+}
+
+// stop closes the channels fed by the read loop
+func (cl *Client) stop() {
+	close(cl.Requests)
+	close(cl.done)
+}
+
+// Done returns a channel which is closed when the connection to the debug
+// adapter is closed
+func (c *Client) Done() <-chan struct{} {
+	return c.done
+}
+
+// Err returns ErrClosed, wrapping the cause if it was not a clean EOF, once
+// Done is closed and nil before
+func (c *Client) Err() error {
+	select {
+	case <-c.done:
+	default:
+		return nil
+	}
+	if c.readErr == nil || errors.Is(c.readErr, io.EOF) || errors.Is(c.readErr, net.ErrClosed) || errors.Is(c.readErr, os.ErrClosed) {
+		return ErrClosed
+	}
+	return fmt.Errorf("%w: %s", ErrClosed, c.readErr)
+}
+
+// ReverseRequest is a request sent by the debug adapter to the client
+type ReverseRequest struct {
+	Seq       int
+	Command   string
+	Arguments json.RawMessage
+}
+
+// decodeReverseRequest decodes content if it is a request
+func decodeReverseRequest(content []byte) (*ReverseRequest, bool) {
+	var message struct {
+		Seq       int             `json:"seq"`
+		Type      string          `json:"type"`
+		Command   string          `json:"command"`
+		Arguments json.RawMessage `json:"arguments"`
+	}
+	if err := json.Unmarshal(content, &message); err != nil || message.Type != "request" {
+		return nil, false
+	}
+	return &ReverseRequest{
+		Seq:       message.Seq,
+		Command:   message.Command,
+		Arguments: message.Arguments,
+	}, true
+}
+
+// NewStdioClient starts cmd and creates a new Client which speaks DAP over
+// its stdin and stdout. cmd must not have been started and its Stdin and
+// Stdout must not be set. Call Close to close the pipes.
+func NewStdioClient(cmd *exec.Cmd) (cl *Client, err error) {
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	err = cmd.Start()
+	if err != nil {
+		return nil, err
+	}
+
+	cl = NewClientFromPipes(stdout, stdin)
+
+	go ReadMessageLoop(cl)
+
+	return
+}
+
+// stdioConn joins a reader and a writer, such as the stdout and stdin of a
+// child process, into a connection
+type stdioConn struct {
+	io.ReadCloser
+	writer io.WriteCloser
+}
+
+func (s *stdioConn) Write(p []byte) (int, error) {
+	return s.writer.Write(p)
+}
+
+func (s *stdioConn) Close() error {
+	writeErr := s.writer.Close()
+	readErr := s.ReadCloser.Close()
+	if writeErr != nil {
+		return writeErr
+	}
+	return readErr
+}
+
+// NewClientFromPipes creates a new Client which reads DAP messages from r and
+// writes them to w. Call Close to close both.
+func NewClientFromPipes(r io.ReadCloser, w io.WriteCloser) *Client {
+	return newClient(&stdioConn{ReadCloser: r, writer: w})
+}
+
 // NewClientFromConn creates a new Client with the given TCP connection.
 // Call Close to close the connection.
 func NewClientFromConn(conn net.Conn) *Client {
+	return newClient(conn)
+}
+
+func newClient(conn io.ReadWriteCloser) *Client {
 	c := &Client{conn: conn, reader: bufio.NewReader(conn)}
 	c.seq = 1 // match VS Code numbering
-	c.Events = make(chan dap.Message, 10)
-	c.Responses = make(chan dap.Message, 10)
+	c.events = &Subscription{client: c, notify: make(chan struct{}, 1)}
+	c.pending = make(map[int]*Future)
+	c.Requests = make(chan *ReverseRequest, 10)
+	c.done = make(chan struct{})
 	return c
 }
 
-// Close closes the client connection.
+// Close closes the client connection. The read loop stops and Done is closed.
 func (c *Client) Close() {
 	_ = c.conn.Close()
 }
 
 func (c *Client) send(request dap.Message) error {
+	c.sendMu.Lock()
+	defer c.sendMu.Unlock()
 	return dap.WriteProtocolMessage(c.conn, request)
 }
 
-func (c *Client) ReadMessage() (dap.Message, error) {
-	return dap.ReadProtocolMessage(c.reader)
+// nextSeq returns the sequence number of the next message sent
+func (c *Client) nextSeq() int {
+	c.seqMu.Lock()
+	defer c.seqMu.Unlock()
+	seq := c.seq
+	c.seq++
+	return seq
 }
 
 // InitializeRequest sends an 'initialize' request.
-func (c *Client) InitializeRequest() error {
+func (c *Client) InitializeRequest() (*Future, error) {
 	request := &dap.InitializeRequest{Request: *c.newRequest("initialize")}
 	request.Arguments = dap.InitializeRequestArguments{
-		AdapterID:                    "concurrency-lab",
-		PathFormat:                   "path",
-		LinesStartAt1:                true,
-		ColumnsStartAt1:              true,
-		SupportsVariableType:         true,
-		SupportsVariablePaging:       true,
-		SupportsRunInTerminalRequest: true,
-		Locale:                       "en-us",
-	}
-	return c.send(request)
-}
-
-func (c *Client) Initialize() (dap.Message, error) {
-	err := c.InitializeRequest()
-	if err != nil {
-		return nil, err
+		AdapterID:              "concurrency-lab",
+		PathFormat:             "path",
+		LinesStartAt1:          true,
+		ColumnsStartAt1:        true,
+		SupportsVariableType:   true,
+		SupportsVariablePaging: true,
+		Locale:                 "en-us",
 	}
-
-	r := <-c.Responses
-
-	c.initializeResponse = r
-	return r, nil
+	return c.call(request)
 }
 
 // InitializeRequestWithArgs sends an 'initialize' request with specified arguments.
-func (c *Client) InitializeRequestWithArgs(args dap.InitializeRequestArguments) (err error) {
+func (c *Client) InitializeRequestWithArgs(args dap.InitializeRequestArguments) (*Future, error) {
 	request := &dap.InitializeRequest{Request: *c.newRequest("initialize")}
 	request.Arguments = args
-	err = c.send(request)
-	return
+	return c.call(request)
 }
 
 func toRawMessage(in interface{}) json.RawMessage {
@@ -129,8 +302,34 @@ func toRawMessage(in interface{}) json.RawMessage {
 	return out
 }
 
+// rawRequest is a request with arguments go-dap does not describe
+type rawRequest struct {
+	dap.Request
+	Arguments map[string]interface{} `json:"arguments,omitempty"`
+}
+
+func (r *rawRequest) GetRequest() *dap.Request { return &r.Request }
+
+// RequestWithArgs sends a request with untyped arguments. This can be used for
+// arguments which are newer than go-dap, e.g. `supportsStartDebuggingRequest`.
+func (c *Client) RequestWithArgs(command string, arguments map[string]interface{}) (*Future, error) {
+	return c.call(&rawRequest{Request: *c.newRequest(command), Arguments: arguments})
+}
+
+// RespondToReverseRequest sends the response to a reverse request
+func (c *Client) RespondToReverseRequest(request *ReverseRequest, success bool, message string) error {
+	response := &dap.Response{}
+	response.Type = "response"
+	response.Seq = c.nextSeq()
+	response.RequestSeq = request.Seq
+	response.Command = request.Command
+	response.Success = success
+	response.Message = message
+	return c.send(response)
+}
+
 // LaunchRequest sends a 'launch' request with the specified args.
-func (c *Client) LaunchRequest(mode, program string, stopOnEntry bool) (err error) {
+func (c *Client) LaunchRequest(mode, program string, stopOnEntry bool) (*Future, error) {
 	request := &dap.LaunchRequest{Request: *c.newRequest("launch")}
 	request.Arguments = toRawMessage(map[string]interface{}{
 		"request":     "launch",
@@ -138,49 +337,48 @@ func (c *Client) LaunchRequest(mode, program string, stopOnEntry bool) (err erro
 		"program":     program,
 		"stopOnEntry": stopOnEntry,
 	})
-	err = c.send(request)
-	return
+	return c.call(request)
 }
 
 // LaunchRequestWithArgs takes a map of untyped implementation-specific
 // arguments to send a 'launch' request. This version can be used to
 // test for values of unexpected types or unspecified values.
-func (c *Client) LaunchRequestWithArgs(arguments map[string]interface{}) error {
+func (c *Client) LaunchRequestWithArgs(arguments map[string]interface{}) (*Future, error) {
 	request := &dap.LaunchRequest{Request: *c.newRequest("launch")}
 	request.Arguments = toRawMessage(arguments)
-	return c.send(request)
+	return c.call(request)
 }
 
 // AttachRequest sends an 'attach' request with the specified
 // arguments.
-func (c *Client) AttachRequest(arguments map[string]interface{}) {
+func (c *Client) AttachRequest(arguments map[string]interface{}) (*Future, error) {
 	request := &dap.AttachRequest{Request: *c.newRequest("attach")}
 	request.Arguments = toRawMessage(arguments)
-	c.send(request)
+	return c.call(request)
 }
 
 // DisconnectRequest sends a 'disconnect' request.
-func (c *Client) DisconnectRequest() {
+func (c *Client) DisconnectRequest() (*Future, error) {
 	request := &dap.DisconnectRequest{Request: *c.newRequest("disconnect")}
-	c.send(request)
+	return c.call(request)
 }
 
 // DisconnectRequestWithKillOption sends a 'disconnect' request with an option to specify
 // `terminateDebuggee`.
-func (c *Client) DisconnectRequestWithKillOption(kill bool) {
+func (c *Client) DisconnectRequestWithKillOption(kill bool) (*Future, error) {
 	request := &dap.DisconnectRequest{Request: *c.newRequest("disconnect")}
 	request.Arguments.TerminateDebuggee = kill
-	c.send(request)
+	return c.call(request)
 }
 
 // SetBreakpointsRequest sends a 'setBreakpoints' request.
-func (c *Client) SetBreakpointsRequest(file string, lines []int) {
-	c.SetBreakpointsRequestWithArgs(file, lines, nil, nil, nil)
+func (c *Client) SetBreakpointsRequest(file string, lines []int) (*Future, error) {
+	return c.SetBreakpointsRequestWithArgs(file, lines, nil, nil, nil)
 }
 
 // SetBreakpointsRequestWithArgs sends a 'setBreakpoints' request with an option to
 // specify conditions, hit conditions, and log messages.
-func (c *Client) SetBreakpointsRequestWithArgs(file string, lines []int, conditions, hitConditions, logMessages map[int]string) error {
+func (c *Client) SetBreakpointsRequestWithArgs(file string, lines []int, conditions, hitConditions, logMessages map[int]string) (*Future, error) {
 	request := &dap.SetBreakpointsRequest{Request: *c.newRequest("setBreakpoints")}
 	request.Arguments = dap.SetBreakpointsArguments{
 		Source: dap.Source{
@@ -201,140 +399,140 @@ func (c *Client) SetBreakpointsRequestWithArgs(file string, lines []int, conditi
 			request.Arguments.Breakpoints[i].LogMessage = logMessage
 		}
 	}
-	return c.send(request)
+	return c.call(request)
 }
 
 // SetExceptionBreakpointsRequest sends a 'setExceptionBreakpoints' request.
-func (c *Client) SetExceptionBreakpointsRequest() {
+func (c *Client) SetExceptionBreakpointsRequest() (*Future, error) {
 	request := &dap.SetBreakpointsRequest{Request: *c.newRequest("setExceptionBreakpoints")}
-	c.send(request)
+	return c.call(request)
 }
 
 // ConfigurationDoneRequest sends a 'configurationDone' request.
-func (c *Client) ConfigurationDoneRequest() error {
+func (c *Client) ConfigurationDoneRequest() (*Future, error) {
 	request := &dap.ConfigurationDoneRequest{Request: *c.newRequest("configurationDone")}
-	return c.send(request)
+	return c.call(request)
 }
 
 // ContinueRequest sends a 'continue' request.
-func (c *Client) ContinueRequest(thread int) error {
+func (c *Client) ContinueRequest(thread int) (*Future, error) {
 	request := &dap.ContinueRequest{Request: *c.newRequest("continue")}
 	request.Arguments.ThreadId = thread
-	return c.send(request)
+	return c.call(request)
 }
 
 // NextRequest sends a 'next' request.
-func (c *Client) NextRequest(thread int) error {
+func (c *Client) NextRequest(thread int) (*Future, error) {
 	request := &dap.NextRequest{Request: *c.newRequest("next")}
 	request.Arguments.ThreadId = thread
-	return c.send(request)
+	return c.call(request)
 }
 
 // NextInstructionRequest sends a 'next' request with granularity 'instruction'.
-func (c *Client) NextInstructionRequest(thread int) {
+func (c *Client) NextInstructionRequest(thread int) (*Future, error) {
 	request := &dap.NextRequest{Request: *c.newRequest("next")}
 	request.Arguments.ThreadId = thread
 	request.Arguments.Granularity = "instruction"
-	c.send(request)
+	return c.call(request)
 }
 
 // StepInRequest sends a 'stepIn' request.
-func (c *Client) StepInRequest(thread int) {
+func (c *Client) StepInRequest(thread int) (*Future, error) {
 	request := &dap.StepInRequest{Request: *c.newRequest("stepIn")}
 	request.Arguments.ThreadId = thread
-	c.send(request)
+	return c.call(request)
 }
 
 // StepInInstructionRequest sends a 'stepIn' request with granularity 'instruction'.
-func (c *Client) StepInInstructionRequest(thread int) {
+func (c *Client) StepInInstructionRequest(thread int) (*Future, error) {
 	request := &dap.StepInRequest{Request: *c.newRequest("stepIn")}
 	request.Arguments.ThreadId = thread
 	request.Arguments.Granularity = "instruction"
-	c.send(request)
+	return c.call(request)
 }
 
 // StepOutRequest sends a 'stepOut' request.
-func (c *Client) StepOutRequest(thread int) {
+func (c *Client) StepOutRequest(thread int) (*Future, error) {
 	request := &dap.StepOutRequest{Request: *c.newRequest("stepOut")}
 	request.Arguments.ThreadId = thread
-	c.send(request)
+	return c.call(request)
 }
 
 // StepOutInstructionRequest sends a 'stepOut' request with granularity 'instruction'.
-func (c *Client) StepOutInstructionRequest(thread int) {
+func (c *Client) StepOutInstructionRequest(thread int) (*Future, error) {
 	request := &dap.StepOutRequest{Request: *c.newRequest("stepOut")}
 	request.Arguments.ThreadId = thread
 	request.Arguments.Granularity = "instruction"
-	c.send(request)
+	return c.call(request)
 }
 
 // PauseRequest sends a 'pause' request.
-func (c *Client) PauseRequest(threadId int) {
+func (c *Client) PauseRequest(threadId int) (*Future, error) {
 	request := &dap.PauseRequest{Request: *c.newRequest("pause")}
 	request.Arguments.ThreadId = threadId
-	c.send(request)
+	return c.call(request)
 }
 
 // ThreadsRequest sends a 'threads' request.
-func (c *Client) ThreadsRequest() error {
+func (c *Client) ThreadsRequest() (*Future, error) {
 	request := &dap.ThreadsRequest{Request: *c.newRequest("threads")}
-	return c.send(request)
+	return c.call(request)
 }
 
 // StackTraceRequest sends a 'stackTrace' request.
-func (c *Client) StackTraceRequest(threadID, startFrame, levels int) {
+func (c *Client) StackTraceRequest(threadID, startFrame, levels int) (*Future, error) {
 	request := &dap.StackTraceRequest{Request: *c.newRequest("stackTrace")}
 	request.Arguments.ThreadId = threadID
 	request.Arguments.StartFrame = startFrame
 	request.Arguments.Levels = levels
-	c.send(request)
+	return c.call(request)
 }
 
 // ScopesRequest sends a 'scopes' request.
-func (c *Client) ScopesRequest(frameID int) {
+func (c *Client) ScopesRequest(frameID int) (*Future, error) {
 	request := &dap.ScopesRequest{Request: *c.newRequest("scopes")}
 	request.Arguments.FrameId = frameID
-	c.send(request)
+	return c.call(request)
 }
 
 // VariablesRequest sends a 'variables' request.
-func (c *Client) VariablesRequest(variablesReference int) {
+func (c *Client) VariablesRequest(variablesReference int) (*Future, error) {
 	request := &dap.VariablesRequest{Request: *c.newRequest("variables")}
 	request.Arguments.VariablesReference = variablesReference
-	c.send(request)
+	return c.call(request)
 }
 
 // IndexedVariablesRequest sends a 'variables' request.
-func (c *Client) IndexedVariablesRequest(variablesReference, start, count int) {
+func (c *Client) IndexedVariablesRequest(variablesReference, start, count int) (*Future, error) {
 	request := &dap.VariablesRequest{Request: *c.newRequest("variables")}
 	request.Arguments.VariablesReference = variablesReference
 	request.Arguments.Filter = "indexed"
 	request.Arguments.Start = start
 	request.Arguments.Count = count
-	c.send(request)
+	return c.call(request)
 }
 
 // NamedVariablesRequest sends a 'variables' request.
-func (c *Client) NamedVariablesRequest(variablesReference int) {
+func (c *Client) NamedVariablesRequest(variablesReference int) (*Future, error) {
 	request := &dap.VariablesRequest{Request: *c.newRequest("variables")}
 	request.Arguments.VariablesReference = variablesReference
 	request.Arguments.Filter = "named"
-	c.send(request)
+	return c.call(request)
 }
 
 // TerminateRequest sends a 'terminate' request.
-func (c *Client) TerminateRequest() {
-	c.send(&dap.TerminateRequest{Request: *c.newRequest("terminate")})
+func (c *Client) TerminateRequest() (*Future, error) {
+	return c.call(&dap.TerminateRequest{Request: *c.newRequest("terminate")})
 }
 
 // RestartRequest sends a 'restart' request.
-func (c *Client) RestartRequest() {
-	c.send(&dap.RestartRequest{Request: *c.newRequest("restart")})
+func (c *Client) RestartRequest() (*Future, error) {
+	return c.call(&dap.RestartRequest{Request: *c.newRequest("restart")})
 }
 
 // SetFunctionBreakpointsRequest sends a 'setFunctionBreakpoints' request.
-func (c *Client) SetFunctionBreakpointsRequest(breakpoints []dap.FunctionBreakpoint) {
-	c.send(&dap.SetFunctionBreakpointsRequest{
+func (c *Client) SetFunctionBreakpointsRequest(breakpoints []dap.FunctionBreakpoint) (*Future, error) {
+	return c.call(&dap.SetFunctionBreakpointsRequest{
 		Request: *c.newRequest("setFunctionBreakpoints"),
 		Arguments: dap.SetFunctionBreakpointsArguments{
 			Breakpoints: breakpoints,
@@ -343,8 +541,8 @@ func (c *Client) SetFunctionBreakpointsRequest(breakpoints []dap.FunctionBreakpo
 }
 
 // SetInstructionBreakpointsRequest sends a 'setInstructionBreakpoints' request.
-func (c *Client) SetInstructionBreakpointsRequest(breakpoints []dap.InstructionBreakpoint) {
-	c.send(&dap.SetInstructionBreakpointsRequest{
+func (c *Client) SetInstructionBreakpointsRequest(breakpoints []dap.InstructionBreakpoint) (*Future, error) {
+	return c.call(&dap.SetInstructionBreakpointsRequest{
 		Request: *c.newRequest("setInstructionBreakpoints"),
 		Arguments: dap.SetInstructionBreakpointsArguments{
 			Breakpoints: breakpoints,
@@ -353,103 +551,103 @@ func (c *Client) SetInstructionBreakpointsRequest(breakpoints []dap.InstructionB
 }
 
 // StepBackRequest sends a 'stepBack' request.
-func (c *Client) StepBackRequest() {
-	c.send(&dap.StepBackRequest{Request: *c.newRequest("stepBack")})
+func (c *Client) StepBackRequest() (*Future, error) {
+	return c.call(&dap.StepBackRequest{Request: *c.newRequest("stepBack")})
 }
 
 // ReverseContinueRequest sends a 'reverseContinue' request.
-func (c *Client) ReverseContinueRequest() {
-	c.send(&dap.ReverseContinueRequest{Request: *c.newRequest("reverseContinue")})
+func (c *Client) ReverseContinueRequest() (*Future, error) {
+	return c.call(&dap.ReverseContinueRequest{Request: *c.newRequest("reverseContinue")})
 }
 
 // SetVariableRequest sends a 'setVariable' request.
-func (c *Client) SetVariableRequest(variablesRef int, name, value string) {
+func (c *Client) SetVariableRequest(variablesRef int, name, value string) (*Future, error) {
 	request := &dap.SetVariableRequest{Request: *c.newRequest("setVariable")}
 	request.Arguments.VariablesReference = variablesRef
 	request.Arguments.Name = name
 	request.Arguments.Value = value
-	c.send(request)
+	return c.call(request)
 }
 
 // RestartFrameRequest sends a 'restartFrame' request.
-func (c *Client) RestartFrameRequest() {
-	c.send(&dap.RestartFrameRequest{Request: *c.newRequest("restartFrame")})
+func (c *Client) RestartFrameRequest() (*Future, error) {
+	return c.call(&dap.RestartFrameRequest{Request: *c.newRequest("restartFrame")})
 }
 
 // GotoRequest sends a 'goto' request.
-func (c *Client) GotoRequest() {
-	c.send(&dap.GotoRequest{Request: *c.newRequest("goto")})
+func (c *Client) GotoRequest() (*Future, error) {
+	return c.call(&dap.GotoRequest{Request: *c.newRequest("goto")})
 }
 
 // SetExpressionRequest sends a 'setExpression' request.
-func (c *Client) SetExpressionRequest() {
-	c.send(&dap.SetExpressionRequest{Request: *c.newRequest("setExpression")})
+func (c *Client) SetExpressionRequest() (*Future, error) {
+	return c.call(&dap.SetExpressionRequest{Request: *c.newRequest("setExpression")})
 }
 
 // SourceRequest sends a 'source' request.
-func (c *Client) SourceRequest() {
-	c.send(&dap.SourceRequest{Request: *c.newRequest("source")})
+func (c *Client) SourceRequest() (*Future, error) {
+	return c.call(&dap.SourceRequest{Request: *c.newRequest("source")})
 }
 
 // TerminateThreadsRequest sends a 'terminateThreads' request.
-func (c *Client) TerminateThreadsRequest() {
-	c.send(&dap.TerminateThreadsRequest{Request: *c.newRequest("terminateThreads")})
+func (c *Client) TerminateThreadsRequest() (*Future, error) {
+	return c.call(&dap.TerminateThreadsRequest{Request: *c.newRequest("terminateThreads")})
 }
 
 // EvaluateRequest sends a 'evaluate' request.
-func (c *Client) EvaluateRequest(expr string, fid int, context string) {
+func (c *Client) EvaluateRequest(expr string, fid int, context string) (*Future, error) {
 	request := &dap.EvaluateRequest{Request: *c.newRequest("evaluate")}
 	request.Arguments.Expression = expr
 	request.Arguments.FrameId = fid
 	request.Arguments.Context = context
-	c.send(request)
+	return c.call(request)
 }
 
 // StepInTargetsRequest sends a 'stepInTargets' request.
-func (c *Client) StepInTargetsRequest() {
-	c.send(&dap.StepInTargetsRequest{Request: *c.newRequest("stepInTargets")})
+func (c *Client) StepInTargetsRequest() (*Future, error) {
+	return c.call(&dap.StepInTargetsRequest{Request: *c.newRequest("stepInTargets")})
 }
 
 // GotoTargetsRequest sends a 'gotoTargets' request.
-func (c *Client) GotoTargetsRequest() {
-	c.send(&dap.GotoTargetsRequest{Request: *c.newRequest("gotoTargets")})
+func (c *Client) GotoTargetsRequest() (*Future, error) {
+	return c.call(&dap.GotoTargetsRequest{Request: *c.newRequest("gotoTargets")})
 }
 
 // CompletionsRequest sends a 'completions' request.
-func (c *Client) CompletionsRequest() {
-	c.send(&dap.CompletionsRequest{Request: *c.newRequest("completions")})
+func (c *Client) CompletionsRequest() (*Future, error) {
+	return c.call(&dap.CompletionsRequest{Request: *c.newRequest("completions")})
 }
 
 // ExceptionInfoRequest sends a 'exceptionInfo' request.
-func (c *Client) ExceptionInfoRequest(threadID int) {
+func (c *Client) ExceptionInfoRequest(threadID int) (*Future, error) {
 	request := &dap.ExceptionInfoRequest{Request: *c.newRequest("exceptionInfo")}
 	request.Arguments.ThreadId = threadID
-	c.send(request)
+	return c.call(request)
 }
 
 // LoadedSourcesRequest sends a 'loadedSources' request.
-func (c *Client) LoadedSourcesRequest() {
-	c.send(&dap.LoadedSourcesRequest{Request: *c.newRequest("loadedSources")})
+func (c *Client) LoadedSourcesRequest() (*Future, error) {
+	return c.call(&dap.LoadedSourcesRequest{Request: *c.newRequest("loadedSources")})
 }
 
 // DataBreakpointInfoRequest sends a 'dataBreakpointInfo' request.
-func (c *Client) DataBreakpointInfoRequest() {
-	c.send(&dap.DataBreakpointInfoRequest{Request: *c.newRequest("dataBreakpointInfo")})
+func (c *Client) DataBreakpointInfoRequest() (*Future, error) {
+	return c.call(&dap.DataBreakpointInfoRequest{Request: *c.newRequest("dataBreakpointInfo")})
 }
 
 // SetDataBreakpointsRequest sends a 'setDataBreakpoints' request.
-func (c *Client) SetDataBreakpointsRequest() {
-	c.send(&dap.SetDataBreakpointsRequest{Request: *c.newRequest("setDataBreakpoints")})
+func (c *Client) SetDataBreakpointsRequest() (*Future, error) {
+	return c.call(&dap.SetDataBreakpointsRequest{Request: *c.newRequest("setDataBreakpoints")})
 }
 
 // ReadMemoryRequest sends a 'readMemory' request.
-func (c *Client) ReadMemoryRequest() {
-	c.send(&dap.ReadMemoryRequest{Request: *c.newRequest("readMemory")})
+func (c *Client) ReadMemoryRequest() (*Future, error) {
+	return c.call(&dap.ReadMemoryRequest{Request: *c.newRequest("readMemory")})
 }
 
 // DisassembleRequest sends a 'disassemble' request.
-func (c *Client) DisassembleRequest(memoryReference string, instructionOffset, inctructionCount int) {
-	c.send(&dap.DisassembleRequest{
+func (c *Client) DisassembleRequest(memoryReference string, instructionOffset, inctructionCount int) (*Future, error) {
+	return c.call(&dap.DisassembleRequest{
 		Request: *c.newRequest("disassemble"),
 		Arguments: dap.DisassembleArguments{
 			MemoryReference:   memoryReference,
@@ -462,18 +660,18 @@ func (c *Client) DisassembleRequest(memoryReference string, instructionOffset, i
 }
 
 // CancelRequest sends a 'cancel' request.
-func (c *Client) CancelRequest() {
-	c.send(&dap.CancelRequest{Request: *c.newRequest("cancel")})
+func (c *Client) CancelRequest() (*Future, error) {
+	return c.call(&dap.CancelRequest{Request: *c.newRequest("cancel")})
 }
 
 // BreakpointLocationsRequest sends a 'breakpointLocations' request.
-func (c *Client) BreakpointLocationsRequest() {
-	c.send(&dap.BreakpointLocationsRequest{Request: *c.newRequest("breakpointLocations")})
+func (c *Client) BreakpointLocationsRequest() (*Future, error) {
+	return c.call(&dap.BreakpointLocationsRequest{Request: *c.newRequest("breakpointLocations")})
 }
 
 // ModulesRequest sends a 'modules' request.
-func (c *Client) ModulesRequest() {
-	c.send(&dap.ModulesRequest{Request: *c.newRequest("modules")})
+func (c *Client) ModulesRequest() (*Future, error) {
+	return c.call(&dap.ModulesRequest{Request: *c.newRequest("modules")})
 }
 
 // UnknownRequest triggers dap.DecodeProtocolMessageFieldError.
@@ -496,6 +694,8 @@ func (c *Client) BadRequest() {
 	content := []byte("{malformedString}")
 	contentLengthHeaderFmt := "Content-Length: %d\r\n\r\n"
 	header := fmt.Sprintf(contentLengthHeaderFmt, len(content))
+	c.sendMu.Lock()
+	defer c.sendMu.Unlock()
 	c.conn.Write([]byte(header))
 	c.conn.Write(content)
 }
@@ -515,79 +715,39 @@ func (c *Client) newRequest(command string) *dap.Request {
 	request := &dap.Request{}
 	request.Type = "request"
 	request.Command = command
-	request.Seq = c.seq
-	c.seq++
+	request.Seq = c.nextSeq()
 	return request
 }
 
-func (c *Client) ReadInitializedEvent() (*dap.InitializedEvent, error) {
-	m := <-c.Events
-	r, ok := m.(*dap.InitializedEvent)
-	if !ok {
-		return nil, fmt.Errorf("Read a message but it was not a dap.InitializedEvent:\n %v+", m)
-	}
-	return r, nil
-}
-
-func (c *Client) ReadLaunchResponse() (*dap.LaunchResponse, error) {
-	m := <-c.Responses
-	r, ok := m.(*dap.LaunchResponse)
-	if !ok {
-		return nil, fmt.Errorf("Read a message but it was not a dap.LaunchResponse")
-	}
-	return r, nil
-}
-
-func (c *Client) ReadSetBreakpointsResponse() (*dap.SetBreakpointsResponse, error) {
-	m := <-c.Responses
-	r, ok := m.(*dap.SetBreakpointsResponse)
-	if !ok {
-		return nil, fmt.Errorf("Read a message but it was not a dap.SetBreakpointsResponse")
-	}
-	return r, nil
+// ReadEvent returns the next event which matches no subscription. It returns
+// the client's error once the connection is closed, or the context's error if
+// ctx is done first.
+func (c *Client) ReadEvent(ctx context.Context) (dap.Message, error) {
+	return c.events.next(ctx, nil)
 }
 
-func (c *Client) ReadStoppedEvent() (*dap.StoppedEvent, error) {
-	m := <-c.Events
-	r, ok := m.(*dap.StoppedEvent)
-	if !ok {
-		return nil, fmt.Errorf("Read a message but it was not a dap.StoppedEvent")
-	}
-	return r, nil
-}
-
-func (c *Client) ReadConfigurationDoneResponse() (*dap.ConfigurationDoneResponse, error) {
-	m := <-c.Responses
-	r, ok := m.(*dap.ConfigurationDoneResponse)
-	if !ok {
-		return nil, fmt.Errorf("Read a message but it was not a dap.ConfigurationDoneResponse")
-	}
-	return r, nil
-}
-
-func (c *Client) ReadContinueResponse() (*dap.ContinueResponse, error) {
-	m := <-c.Responses
-	r, ok := m.(*dap.ContinueResponse)
-	if !ok {
-		return nil, fmt.Errorf("Read a message but it was not a dap.ContinueResponse")
-	}
-	return r, nil
-}
-
-func (c *Client) ReadThreadsResponse() (*dap.ThreadsResponse, error) {
-	m := <-c.Responses
-	r, ok := m.(*dap.ThreadsResponse)
-	if !ok {
-		return nil, fmt.Errorf("Read a message but it was not a dap.ThreadsResponse")
-	}
-	return r, nil
-}
-
-func (c *Client) ReadTerminatedEvent() (*dap.TerminatedEvent, error) {
-	m := <-c.Events
-	r, ok := m.(*dap.TerminatedEvent)
-	if !ok {
-		return nil, fmt.Errorf("Read a message but it was not a dap.TerminatedEvent")
+// ReadReverseRequestWithTimeout returns the next reverse request with the
+// given command, or ErrTimeout. Other reverse requests are refused.
+func (c *Client) ReadReverseRequestWithTimeout(ctx context.Context, command string, timeout time.Duration) (*ReverseRequest, error) {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	for {
+		select {
+		case request, ok := <-c.Requests:
+			if !ok {
+				return nil, c.Err()
+			}
+			if request.Command == command {
+				return request, nil
+			}
+			err := c.RespondToReverseRequest(request, false, "not supported")
+			if err != nil {
+				return nil, err
+			}
+		case <-timer.C:
+			return nil, ErrTimeout
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
 	}
-	return r, nil
 }
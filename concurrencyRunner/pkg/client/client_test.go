@@ -0,0 +1,56 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestClientStopsOnEOF(t *testing.T) {
+	cl, fake := newFakeAdapter(t)
+
+	if err := cl.Err(); err != nil {
+		t.Errorf("got error %v before the connection closed", err)
+	}
+	select {
+	case <-cl.Done():
+		t.Fatal("done before the connection closed")
+	default:
+	}
+
+	fake.close()
+	select {
+	case <-cl.Done():
+	case <-time.After(time.Second):
+		t.Fatal("not done after the connection closed")
+	}
+
+	// a clean EOF is not wrapped
+	if err := cl.Err(); err != ErrClosed {
+		t.Errorf("got %v, want %v", err, ErrClosed)
+	}
+	if _, ok := <-cl.Requests; ok {
+		t.Error("Requests is not closed")
+	}
+}
+
+func TestClientClose(t *testing.T) {
+	cl, _ := newFakeAdapter(t)
+
+	cl.Close()
+	select {
+	case <-cl.Done():
+	case <-time.After(time.Second):
+		t.Fatal("not done after Close")
+	}
+	if err := cl.Err(); !errors.Is(err, ErrClosed) {
+		t.Errorf("got %v, want %v", err, ErrClosed)
+	}
+
+	// requests fail rather than block
+	_, err := cl.Threads(context.Background())
+	if err == nil {
+		t.Error("expected an error")
+	}
+}
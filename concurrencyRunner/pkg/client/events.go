@@ -0,0 +1,183 @@
+package client
+
+import (
+	"context"
+	"github.com/google/go-dap"
+	"sync"
+	"time"
+)
+
+// EventFilter selects the events delivered to a subscription
+type EventFilter func(event dap.EventMessage) bool
+
+// EventTypes matches events with any of the given names, e.g. "stopped"
+func EventTypes(events ...string) EventFilter {
+	return func(event dap.EventMessage) bool {
+		name := event.GetEvent().Event
+		for _, e := range events {
+			if e == name {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// OutputCategories matches output events with any of the given categories.
+// Output without a category is "console". Other events do not match.
+func OutputCategories(categories ...string) EventFilter {
+	return func(event dap.EventMessage) bool {
+		output, ok := event.(*dap.OutputEvent)
+		if !ok {
+			return false
+		}
+		category := output.Body.Category
+		if category == "" {
+			category = "console"
+		}
+		for _, c := range categories {
+			if c == category {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// Subscription queues the events which match all of its filters. The queue
+// is unbounded so a slow subscriber never blocks the read loop.
+type Subscription struct {
+	client  *Client
+	filters []EventFilter
+
+	mu     sync.Mutex
+	queue  []dap.EventMessage
+	notify chan struct{}
+}
+
+// Subscribe returns a subscription to the events which match all filters.
+// Every matching subscription receives an event. Events which match no
+// subscription can be read with ReadEvent until DropUnmatchedEvents is called.
+func (c *Client) Subscribe(filters ...EventFilter) *Subscription {
+	s := &Subscription{
+		client:  c,
+		filters: filters,
+		notify:  make(chan struct{}, 1),
+	}
+	c.subscriptionsMu.Lock()
+	c.subscriptions = append(c.subscriptions, s)
+	c.subscriptionsMu.Unlock()
+	return s
+}
+
+// Unsubscribe stops delivery to s. Queued events can still be read.
+func (s *Subscription) Unsubscribe() {
+	c := s.client
+	c.subscriptionsMu.Lock()
+	defer c.subscriptionsMu.Unlock()
+	for i, sub := range c.subscriptions {
+		if sub == s {
+			c.subscriptions = append(c.subscriptions[:i], c.subscriptions[i+1:]...)
+			return
+		}
+	}
+}
+
+func (s *Subscription) matches(event dap.EventMessage) bool {
+	for _, filter := range s.filters {
+		if !filter(event) {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *Subscription) deliver(event dap.EventMessage) {
+	s.mu.Lock()
+	s.queue = append(s.queue, event)
+	s.mu.Unlock()
+	s.wake()
+}
+
+func (s *Subscription) wake() {
+	select {
+	case s.notify <- struct{}{}:
+	default:
+	}
+}
+
+func (s *Subscription) pop() (dap.EventMessage, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.queue) == 0 {
+		return nil, false
+	}
+	event := s.queue[0]
+	s.queue = s.queue[1:]
+	return event, true
+}
+
+// Next returns the next event. Once the connection is closed and the queue is
+// empty it returns the client's error.
+func (s *Subscription) Next(ctx context.Context) (dap.EventMessage, error) {
+	return s.next(ctx, nil)
+}
+
+// NextWithTimeout returns the next event or ErrTimeout if none arrives within
+// timeout
+func (s *Subscription) NextWithTimeout(ctx context.Context, timeout time.Duration) (dap.EventMessage, error) {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	return s.next(ctx, timer.C)
+}
+
+func (s *Subscription) next(ctx context.Context, timeout <-chan time.Time) (dap.EventMessage, error) {
+	for {
+		if event, ok := s.pop(); ok {
+			return event, nil
+		}
+		select {
+		case <-s.notify:
+		case <-s.client.Done():
+			// events may have been queued just before the close
+			if event, ok := s.pop(); ok {
+				return event, nil
+			}
+			return nil, s.client.Err()
+		case <-timeout:
+			return nil, ErrTimeout
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// DropUnmatchedEvents discards the events which match no subscription from now
+// on, along with those already queued. Call it once nothing reads them with
+// ReadEvent any more so they do not pile up.
+func (c *Client) DropUnmatchedEvents() {
+	c.subscriptionsMu.Lock()
+	c.dropUnmatched = true
+	c.subscriptionsMu.Unlock()
+
+	c.events.mu.Lock()
+	c.events.queue = nil
+	c.events.mu.Unlock()
+}
+
+// dispatch delivers event to every matching subscription, or to the client's
+// own queue if none match
+func (c *Client) dispatch(event dap.EventMessage) {
+	c.subscriptionsMu.Lock()
+	defer c.subscriptionsMu.Unlock()
+	matched := false
+	for _, s := range c.subscriptions {
+		if s.matches(event) {
+			s.deliver(event)
+			matched = true
+		}
+	}
+	if !matched && !c.dropUnmatched {
+		c.events.deliver(event)
+	}
+}
@@ -0,0 +1,124 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"github.com/google/go-dap"
+	"testing"
+	"time"
+)
+
+// output returns an output event for the given category
+func output(category, text string) *dap.OutputEvent {
+	event := &dap.OutputEvent{}
+	event.Body.Category = category
+	event.Body.Output = text
+	return event
+}
+
+func nextEvent(t *testing.T, s *Subscription) dap.EventMessage {
+	t.Helper()
+	m, err := s.NextWithTimeout(context.Background(), time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func TestSubscriptionFanOut(t *testing.T) {
+	cl, fake := newFakeAdapter(t)
+
+	execution := cl.Subscribe(EventTypes("stopped", "exited"))
+	stdout := cl.Subscribe(OutputCategories("stdout"))
+	all := cl.Subscribe(OutputCategories("stdout", "stderr"))
+
+	fake.event(output("stdout", "one"), "output")
+	fake.event(&dap.StoppedEvent{}, "stopped")
+	fake.event(output("stderr", "two"), "output")
+	fake.event(&dap.ExitedEvent{}, "exited")
+
+	if _, ok := nextEvent(t, execution).(*dap.StoppedEvent); !ok {
+		t.Error("expected the stopped event")
+	}
+	if _, ok := nextEvent(t, execution).(*dap.ExitedEvent); !ok {
+		t.Error("expected the exited event")
+	}
+
+	// every matching subscription receives an event
+	if m := nextEvent(t, stdout).(*dap.OutputEvent); m.Body.Output != "one" {
+		t.Errorf("got output %q, want one", m.Body.Output)
+	}
+	for _, want := range []string{"one", "two"} {
+		if m := nextEvent(t, all).(*dap.OutputEvent); m.Body.Output != want {
+			t.Errorf("got output %q, want %q", m.Body.Output, want)
+		}
+	}
+
+	_, err := stdout.NextWithTimeout(context.Background(), 50*time.Millisecond)
+	if !errors.Is(err, ErrTimeout) {
+		t.Errorf("got %v, want %v", err, ErrTimeout)
+	}
+
+	// output without a category is console output
+	console := cl.Subscribe(OutputCategories("console"))
+	fake.event(output("", "three"), "output")
+	if m := nextEvent(t, console).(*dap.OutputEvent); m.Body.Output != "three" {
+		t.Errorf("got output %q, want three", m.Body.Output)
+	}
+}
+
+func TestSubscriptionAfterClose(t *testing.T) {
+	cl, fake := newFakeAdapter(t)
+	sub := cl.Subscribe(EventTypes("terminated"))
+
+	fake.event(&dap.TerminatedEvent{}, "terminated")
+	fake.close()
+	<-cl.Done()
+
+	// events queued before the close are still returned
+	if _, ok := nextEvent(t, sub).(*dap.TerminatedEvent); !ok {
+		t.Error("expected the terminated event")
+	}
+	_, err := sub.Next(context.Background())
+	if !errors.Is(err, ErrClosed) {
+		t.Errorf("got %v, want %v", err, ErrClosed)
+	}
+}
+
+func TestUnmatchedEvents(t *testing.T) {
+	cl, fake := newFakeAdapter(t)
+	ctx := context.Background()
+	sub := cl.Subscribe(EventTypes("stopped"))
+
+	fake.event(&dap.InitializedEvent{}, "initialized")
+	fake.event(&dap.StoppedEvent{}, "stopped")
+
+	// unmatched events are queued until they are dropped
+	m, err := cl.ReadEvent(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := m.(*dap.InitializedEvent); !ok {
+		t.Errorf("got %T, want the initialized event", m)
+	}
+	nextEvent(t, sub)
+
+	fake.event(output("stdout", "queued"), "output")
+	fake.event(&dap.StoppedEvent{}, "stopped")
+	nextEvent(t, sub)
+	cl.DropUnmatchedEvents()
+	fake.event(output("stdout", "dropped"), "output")
+	fake.event(&dap.StoppedEvent{}, "stopped")
+	nextEvent(t, sub)
+
+	ctx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	m, err = cl.ReadEvent(ctx)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("got %v and %T, want no event", err, m)
+	}
+
+	// subscriptions still receive events
+	fake.event(&dap.StoppedEvent{}, "stopped")
+	nextEvent(t, sub)
+}
@@ -0,0 +1,300 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"github.com/google/go-dap"
+)
+
+// Future is the pending response to a request. Responses are matched to
+// requests by their request_seq so they may arrive in any order.
+type Future struct {
+	Seq     int
+	Command string
+	client  *Client
+
+	// response is set before done is closed
+	response dap.ResponseMessage
+	done     chan struct{}
+}
+
+// ResponseError is returned for an error response from the debug adapter
+type ResponseError struct {
+	Command string
+	Message string
+}
+
+func (e *ResponseError) Error() string {
+	return fmt.Sprintf("%s failed: %s", e.Command, e.Message)
+}
+
+// Done returns a channel which is closed once the response has arrived
+func (f *Future) Done() <-chan struct{} {
+	return f.done
+}
+
+// Wait returns the response to the request. Error responses are returned as
+// a *ResponseError. If the connection closes first the client's error is
+// returned. Wait may be called more than once, unless ctx is done first in
+// which case the response is no longer awaited.
+func (f *Future) Wait(ctx context.Context) (dap.ResponseMessage, error) {
+	select {
+	case <-f.done:
+		return f.result(f.response)
+	case <-f.client.Done():
+		// the response may have been delivered just before the close
+		select {
+		case <-f.done:
+			return f.result(f.response)
+		default:
+		}
+		return nil, fmt.Errorf("waiting for %s response: %w", f.Command, f.client.Err())
+	case <-ctx.Done():
+		f.client.forget(f)
+		return nil, fmt.Errorf("waiting for %s response: %w", f.Command, ctx.Err())
+	}
+}
+
+func (f *Future) result(m dap.ResponseMessage) (dap.ResponseMessage, error) {
+	if e, ok := m.(*dap.ErrorResponse); ok {
+		message := e.Message
+		if e.Body.Error.Format != "" {
+			message = e.Body.Error.Format
+		}
+		return nil, &ResponseError{Command: f.Command, Message: message}
+	}
+	return m, nil
+}
+
+// call sends a request and returns a future for its response
+func (c *Client) call(request dap.RequestMessage) (*Future, error) {
+	r := request.GetRequest()
+	f := &Future{
+		Seq:     r.Seq,
+		Command: r.Command,
+		client:  c,
+		done:    make(chan struct{}),
+	}
+
+	c.pendingMu.Lock()
+	c.pending[f.Seq] = f
+	c.pendingMu.Unlock()
+
+	err := c.send(request)
+	if err != nil {
+		c.pendingMu.Lock()
+		delete(c.pending, f.Seq)
+		c.pendingMu.Unlock()
+		return nil, err
+	}
+	return f, nil
+}
+
+// resolve delivers a response to the future of its request. Responses to
+// unknown requests are dropped.
+func (c *Client) resolve(m dap.ResponseMessage) {
+	seq := m.GetResponse().RequestSeq
+
+	c.pendingMu.Lock()
+	f, ok := c.pending[seq]
+	delete(c.pending, seq)
+	c.pendingMu.Unlock()
+
+	if ok {
+		f.response = m
+		close(f.done)
+	}
+}
+
+// forget drops f from the requests awaiting a response
+func (c *Client) forget(f *Future) {
+	c.pendingMu.Lock()
+	defer c.pendingMu.Unlock()
+	if c.pending[f.Seq] == f {
+		delete(c.pending, f.Seq)
+	}
+}
+
+// waitFor waits for the response of f and checks that it is a T
+func waitFor[T dap.ResponseMessage](ctx context.Context, f *Future) (r T, err error) {
+	m, err := f.Wait(ctx)
+	if err != nil {
+		return r, err
+	}
+	r, ok := m.(T)
+	if !ok {
+		return r, fmt.Errorf("%s: expected %T, got %T", f.Command, r, m)
+	}
+	return r, nil
+}
+
+// WaitLaunch waits for the response to a 'launch' request
+func WaitLaunch(ctx context.Context, f *Future) (*dap.LaunchResponse, error) {
+	return waitFor[*dap.LaunchResponse](ctx, f)
+}
+
+// WaitAttach waits for the response to an 'attach' request
+func WaitAttach(ctx context.Context, f *Future) (*dap.AttachResponse, error) {
+	return waitFor[*dap.AttachResponse](ctx, f)
+}
+
+/****************************************************
+ * Typed requests
+ ***************************************************/
+
+// Initialize sends an 'initialize' request and waits for the response
+func (c *Client) Initialize(ctx context.Context) (*dap.InitializeResponse, error) {
+	f, err := c.InitializeRequest()
+	if err != nil {
+		return nil, err
+	}
+	r, err := waitFor[*dap.InitializeResponse](ctx, f)
+	if err != nil {
+		return nil, err
+	}
+	c.initializeResponse = r
+	return r, nil
+}
+
+// InitializeWithArgs sends an 'initialize' request with untyped arguments and
+// waits for the response
+func (c *Client) InitializeWithArgs(ctx context.Context, arguments map[string]interface{}) (*dap.InitializeResponse, error) {
+	f, err := c.RequestWithArgs("initialize", arguments)
+	if err != nil {
+		return nil, err
+	}
+	r, err := waitFor[*dap.InitializeResponse](ctx, f)
+	if err != nil {
+		return nil, err
+	}
+	c.initializeResponse = r
+	return r, nil
+}
+
+// SetBreakpoints sends a 'setBreakpoints' request and waits for the response
+func (c *Client) SetBreakpoints(ctx context.Context, file string, lines []int, conditions, hitConditions, logMessages map[int]string) (*dap.SetBreakpointsResponse, error) {
+	f, err := c.SetBreakpointsRequestWithArgs(file, lines, conditions, hitConditions, logMessages)
+	if err != nil {
+		return nil, err
+	}
+	return waitFor[*dap.SetBreakpointsResponse](ctx, f)
+}
+
+// ConfigurationDone sends a 'configurationDone' request and waits for the
+// response
+func (c *Client) ConfigurationDone(ctx context.Context) (*dap.ConfigurationDoneResponse, error) {
+	f, err := c.ConfigurationDoneRequest()
+	if err != nil {
+		return nil, err
+	}
+	return waitFor[*dap.ConfigurationDoneResponse](ctx, f)
+}
+
+// Disconnect sends a 'disconnect' request and waits for the response. kill
+// terminates the debuggee, otherwise the adapter detaches from it.
+func (c *Client) Disconnect(ctx context.Context, kill bool) (*dap.DisconnectResponse, error) {
+	f, err := c.DisconnectRequestWithKillOption(kill)
+	if err != nil {
+		return nil, err
+	}
+	return waitFor[*dap.DisconnectResponse](ctx, f)
+}
+
+// Continue sends a 'continue' request and waits for the response
+func (c *Client) Continue(ctx context.Context, thread int) (*dap.ContinueResponse, error) {
+	f, err := c.ContinueRequest(thread)
+	if err != nil {
+		return nil, err
+	}
+	return waitFor[*dap.ContinueResponse](ctx, f)
+}
+
+// Next sends a 'next' request and waits for the response
+func (c *Client) Next(ctx context.Context, thread int) (*dap.NextResponse, error) {
+	f, err := c.NextRequest(thread)
+	if err != nil {
+		return nil, err
+	}
+	return waitFor[*dap.NextResponse](ctx, f)
+}
+
+// StepIn sends a 'stepIn' request and waits for the response
+func (c *Client) StepIn(ctx context.Context, thread int) (*dap.StepInResponse, error) {
+	f, err := c.StepInRequest(thread)
+	if err != nil {
+		return nil, err
+	}
+	return waitFor[*dap.StepInResponse](ctx, f)
+}
+
+// StepOut sends a 'stepOut' request and waits for the response
+func (c *Client) StepOut(ctx context.Context, thread int) (*dap.StepOutResponse, error) {
+	f, err := c.StepOutRequest(thread)
+	if err != nil {
+		return nil, err
+	}
+	return waitFor[*dap.StepOutResponse](ctx, f)
+}
+
+// Pause sends a 'pause' request and waits for the response
+func (c *Client) Pause(ctx context.Context, thread int) (*dap.PauseResponse, error) {
+	f, err := c.PauseRequest(thread)
+	if err != nil {
+		return nil, err
+	}
+	return waitFor[*dap.PauseResponse](ctx, f)
+}
+
+// Threads sends a 'threads' request and waits for the response
+func (c *Client) Threads(ctx context.Context) (*dap.ThreadsResponse, error) {
+	f, err := c.ThreadsRequest()
+	if err != nil {
+		return nil, err
+	}
+	return waitFor[*dap.ThreadsResponse](ctx, f)
+}
+
+// StackTrace sends a 'stackTrace' request and waits for the response
+func (c *Client) StackTrace(ctx context.Context, thread, startFrame, levels int) (*dap.StackTraceResponse, error) {
+	f, err := c.StackTraceRequest(thread, startFrame, levels)
+	if err != nil {
+		return nil, err
+	}
+	return waitFor[*dap.StackTraceResponse](ctx, f)
+}
+
+// Scopes sends a 'scopes' request and waits for the response
+func (c *Client) Scopes(ctx context.Context, frameId int) (*dap.ScopesResponse, error) {
+	f, err := c.ScopesRequest(frameId)
+	if err != nil {
+		return nil, err
+	}
+	return waitFor[*dap.ScopesResponse](ctx, f)
+}
+
+// Variables sends a 'variables' request and waits for the response
+func (c *Client) Variables(ctx context.Context, variablesReference int) (*dap.VariablesResponse, error) {
+	f, err := c.VariablesRequest(variablesReference)
+	if err != nil {
+		return nil, err
+	}
+	return waitFor[*dap.VariablesResponse](ctx, f)
+}
+
+// SetVariable sends a 'setVariable' request and waits for the response
+func (c *Client) SetVariable(ctx context.Context, variablesReference int, name, value string) (*dap.SetVariableResponse, error) {
+	f, err := c.SetVariableRequest(variablesReference, name, value)
+	if err != nil {
+		return nil, err
+	}
+	return waitFor[*dap.SetVariableResponse](ctx, f)
+}
+
+// Evaluate sends an 'evaluate' request and waits for the response
+func (c *Client) Evaluate(ctx context.Context, expr string, frameId int, context string) (*dap.EvaluateResponse, error) {
+	f, err := c.EvaluateRequest(expr, frameId, context)
+	if err != nil {
+		return nil, err
+	}
+	return waitFor[*dap.EvaluateResponse](ctx, f)
+}
@@ -0,0 +1,222 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"github.com/google/go-dap"
+	"io"
+	"testing"
+	"time"
+)
+
+// fakeAdapter is the debug adapter end of a client connection
+type fakeAdapter struct {
+	t        *testing.T
+	writer   *io.PipeWriter
+	requests chan dap.Message
+	seq      int
+}
+
+// newFakeAdapter returns a client connected to a fake adapter. Requests sent
+// by the client are read from the adapter's requests channel.
+func newFakeAdapter(t *testing.T) (*Client, *fakeAdapter) {
+	clientReader, adapterWriter := io.Pipe()
+	adapterReader, clientWriter := io.Pipe()
+	cl := NewClientFromPipes(clientReader, clientWriter)
+	go ReadMessageLoop(cl)
+	t.Cleanup(cl.Close)
+
+	f := &fakeAdapter{t: t, writer: adapterWriter, requests: make(chan dap.Message, 10)}
+	go func() {
+		reader := bufio.NewReader(adapterReader)
+		for {
+			m, err := dap.ReadProtocolMessage(reader)
+			if err != nil {
+				close(f.requests)
+				return
+			}
+			f.requests <- m
+		}
+	}()
+	return cl, f
+}
+
+// request returns the next request sent by the client
+func (f *fakeAdapter) request() dap.RequestMessage {
+	select {
+	case m, ok := <-f.requests:
+		if !ok {
+			f.t.Fatal("client connection closed")
+		}
+		return m.(dap.RequestMessage)
+	case <-time.After(time.Second):
+		f.t.Fatal("no request from the client")
+	}
+	return nil
+}
+
+// send writes a message to the client
+func (f *fakeAdapter) send(m dap.Message) {
+	err := dap.WriteProtocolMessage(f.writer, m)
+	if err != nil {
+		f.t.Error(err)
+	}
+}
+
+// respond sends a successful response to request
+func (f *fakeAdapter) respond(request dap.RequestMessage, response dap.ResponseMessage) {
+	f.prepare(request, response)
+	response.GetResponse().Success = true
+	f.send(response)
+}
+
+// respondWithError sends an error response to request
+func (f *fakeAdapter) respondWithError(request dap.RequestMessage, format string) {
+	response := &dap.ErrorResponse{}
+	f.prepare(request, response)
+	response.Body.Error.Format = format
+	f.send(response)
+}
+
+func (f *fakeAdapter) prepare(request dap.RequestMessage, response dap.ResponseMessage) {
+	f.seq++
+	r := response.GetResponse()
+	r.Seq = f.seq
+	r.Type = "response"
+	r.RequestSeq = request.GetRequest().Seq
+	r.Command = request.GetRequest().Command
+}
+
+// event sends an event with the given name
+func (f *fakeAdapter) event(event dap.EventMessage, name string) {
+	f.seq++
+	e := event.GetEvent()
+	e.Seq = f.seq
+	e.Type = "event"
+	e.Event = name
+	f.send(event)
+}
+
+// close ends the connection
+func (f *fakeAdapter) close() {
+	f.writer.Close()
+}
+
+func TestFutureOutOfOrderResponses(t *testing.T) {
+	cl, fake := newFakeAdapter(t)
+	ctx := context.Background()
+
+	threads, err := cl.ThreadsRequest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cont, err := cl.ContinueRequest(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	threadsRequest := fake.request()
+	continueRequest := fake.request()
+
+	fake.respond(continueRequest, &dap.ContinueResponse{})
+	fake.respond(threadsRequest, &dap.ThreadsResponse{})
+
+	_, err = waitFor[*dap.ThreadsResponse](ctx, threads)
+	if err != nil {
+		t.Error(err)
+	}
+	_, err = waitFor[*dap.ContinueResponse](ctx, cont)
+	if err != nil {
+		t.Error(err)
+	}
+
+	// the response is kept for later calls
+	_, err = waitFor[*dap.ContinueResponse](ctx, cont)
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+func TestFutureErrorResponse(t *testing.T) {
+	cl, fake := newFakeAdapter(t)
+
+	f, err := cl.ContinueRequest(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fake.respondWithError(fake.request(), "no such thread")
+
+	_, err = f.Wait(context.Background())
+	var responseErr *ResponseError
+	if !errors.As(err, &responseErr) {
+		t.Fatalf("got %v, want a *ResponseError", err)
+	}
+	if responseErr.Command != "continue" || responseErr.Message != "no such thread" {
+		t.Errorf("got %+v", responseErr)
+	}
+}
+
+func TestFutureConnectionClosed(t *testing.T) {
+	cl, fake := newFakeAdapter(t)
+	ctx := context.Background()
+
+	answered, err := cl.ThreadsRequest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	unanswered, err := cl.ContinueRequest(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fake.respond(fake.request(), &dap.ThreadsResponse{})
+	fake.request()
+	fake.close()
+	<-cl.Done()
+
+	// a response which arrived before the close is still returned
+	_, err = waitFor[*dap.ThreadsResponse](ctx, answered)
+	if err != nil {
+		t.Error(err)
+	}
+
+	_, err = unanswered.Wait(ctx)
+	if !errors.Is(err, ErrClosed) {
+		t.Errorf("got %v, want %v", err, ErrClosed)
+	}
+}
+
+func TestFutureContextDone(t *testing.T) {
+	cl, fake := newFakeAdapter(t)
+
+	f, err := cl.ContinueRequest(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	request := fake.request()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = f.Wait(ctx)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("got %v, want %v", err, context.Canceled)
+	}
+
+	cl.pendingMu.Lock()
+	_, pending := cl.pending[f.Seq]
+	cl.pendingMu.Unlock()
+	if pending {
+		t.Error("request is still pending")
+	}
+
+	// a late response is dropped
+	fake.respond(request, &dap.ContinueResponse{})
+	threads, err := cl.ThreadsRequest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	fake.respond(fake.request(), &dap.ThreadsResponse{})
+	_, err = waitFor[*dap.ThreadsResponse](context.Background(), threads)
+	if err != nil {
+		t.Error(err)
+	}
+}
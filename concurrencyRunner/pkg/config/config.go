@@ -11,13 +11,51 @@ import (
 type Instance struct {
 	Id       string
 	Name     string
-	Adapter  AdapterEnum
+	Adapter  string
 	Program  string
 	Env      string
 	Cwd      string
 	SrcRoot  string
 	Args     []string
 	OutputBg color.Color
+	// AdapterPath overrides the adapter executable or script, e.g. the path
+	// to `dlv`, the python interpreter or js-debug's dapDebugServer.js
+	AdapterPath string
+	// Address is the host:port of a DAP server which is already running. The
+	// runner connects to it instead of starting an adapter. Use request
+	// "attach" for a `dlv --headless --accept-multiclient` server.
+	Address string
+	// Request is "launch" (the default) to start Program or "attach" to
+	// attach to a running process identified by Pid or ProcessName
+	Request     RequestEnum
+	Pid         int
+	ProcessName string
+	// LogFile is a file to which the program output of the instance is
+	// appended, in addition to STDOUT. In explore mode it collects the
+	// output of every schedule.
+	LogFile string
+	// Logpoints print messages as the instance passes target comments
+	// without pausing it
+	Logpoints []Logpoint
+	// PausePoints are the ordered target comments the instance passes
+	// through. Used by explore mode to generate schedules.
+	PausePoints []PausePoint
+}
+
+// Logpoint prints Message whenever the instance reaches TargetComment.
+// Expressions in braces, e.g. "unreadCount={unreadCount}", are interpolated
+// by the adapter.
+type Logpoint struct {
+	File          string
+	TargetComment string
+	Message       string
+}
+
+type PausePoint struct {
+	File          string
+	TargetComment string
+	Condition     string
+	HitCondition  string
 }
 
 type Action struct {
@@ -26,11 +64,80 @@ type Action struct {
 	File          string
 	TargetComment string
 	SleepDuration time.Duration `json:"duration,omitempty"`
+	// Window in seconds for expectBlocked and expectUnblocked
+	Window time.Duration `json:"window,omitempty"`
+	// Timeout in seconds for actions which wait on an instance. Defaults to
+	// the config's Timeout.
+	Timeout time.Duration `json:"timeout,omitempty"`
+	// Condition and HitCondition make a pause conditional, e.g.
+	// "employee_id == 2" or a hit count of "3". Their syntax is the
+	// adapter's.
+	Condition    string
+	HitCondition string
+	// Pattern is a regular expression matched against each line of program
+	// output by waitForOutput
+	Pattern string
+	// ExpectExitCode is the exit code expected by waitExit. Any exit code is
+	// accepted when it is not set.
+	ExpectExitCode *int
+	// Count is the number of steps taken by next, stepIn and stepOut.
+	// Defaults to 1.
+	Count int
+	// Capture lists variable names or expressions whose values are printed
+	// and recorded when a pause or step action stops the instance
+	Capture []string
+	// Variable and Value are the local variable overwritten by set and its
+	// new value. Alternatively set evaluates Expression, e.g. an assignment.
+	Variable   string
+	Value      string
+	Expression string
+	// Expect is the value of Expression expected by assert. When it is not
+	// set Expression is a predicate which must be true.
+	Expect string
+	// ContinueOnError reports errors from this action without aborting
+	// the sequence
+	ContinueOnError bool
+}
+
+// Invariant is checked at the end of a scenario. Shell invariants run Command
+// and compare its exit code and output, evaluate invariants evaluate
+// Expression in a paused instance and compare the result to Expect.
+type Invariant struct {
+	Name           string
+	Type           InvariantTypeEnum `json:"type,omitempty"`
+	Command        string
+	Cwd            string
+	ExpectExitCode int
+	// ExpectOutput is a regular expression matched against the command output
+	ExpectOutput string
+	InstanceId   string
+	Expression   string
+	Expect       string
+}
+
+type ExploreOptions struct {
+	// MaxSchedules limits the number of schedules run, 0 means no limit
+	MaxSchedules int
+}
+
+// Hook is a shell command run by the runner
+type Hook struct {
+	Command string
+	Cwd     string
 }
 
 type Config struct {
-	Instances []Instance
-	Sequence  []Action
+	Mode ModeEnum
+	// Timeout is the default action timeout in seconds
+	Timeout    time.Duration `json:"timeout,omitempty"`
+	Instances  []Instance
+	Sequence   []Action
+	Explore    ExploreOptions
+	Invariants []Invariant
+	// Setup commands run before each scenario, so before every schedule in
+	// explore mode. The runner does not reset external state such as
+	// database rows between scenarios, use Setup to do so.
+	Setup []Hook
 }
 
 func ReadConfigFile(path string) (config *Config, err error) {
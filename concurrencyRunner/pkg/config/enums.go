@@ -14,19 +14,37 @@ const (
 	ActionTypePause
 	ActionTypeContinue
 	ActionTypeSleep
+	ActionTypeExpectBlocked
+	ActionTypeExpectUnblocked
+	ActionTypeWaitForOutput
+	ActionTypeWaitExit
+	ActionTypeNext
+	ActionTypeStepIn
+	ActionTypeStepOut
+	ActionTypeSet
+	ActionTypeAssert
 )
 
 func (t ActionTypeEnum) String() string {
-	return [...]string{"unknown", "run", "pause", "continue", "sleep"}[t]
+	return [...]string{"unknown", "run", "pause", "continue", "sleep", "expectBlocked", "expectUnblocked", "waitForOutput", "waitExit", "next", "stepIn", "stepOut", "set", "assert"}[t]
 }
 
 func (t *ActionTypeEnum) FromString(Action string) ActionTypeEnum {
 	return map[string]ActionTypeEnum{
-		"unknown":  ActionTypeUnknown,
-		"run":      ActionTypeRun,
-		"pause":    ActionTypePause,
-		"continue": ActionTypeContinue,
-		"sleep":    ActionTypeSleep,
+		"unknown":         ActionTypeUnknown,
+		"run":             ActionTypeRun,
+		"pause":           ActionTypePause,
+		"continue":        ActionTypeContinue,
+		"sleep":           ActionTypeSleep,
+		"expectBlocked":   ActionTypeExpectBlocked,
+		"expectUnblocked": ActionTypeExpectUnblocked,
+		"waitForOutput":   ActionTypeWaitForOutput,
+		"waitExit":        ActionTypeWaitExit,
+		"next":            ActionTypeNext,
+		"stepIn":          ActionTypeStepIn,
+		"stepOut":         ActionTypeStepOut,
+		"set":             ActionTypeSet,
+		"assert":          ActionTypeAssert,
 	}[Action]
 }
 
@@ -45,32 +63,110 @@ func (t *ActionTypeEnum) UnmarshalJSON(b []byte) error {
 }
 
 /**************************************
- * AdapterEnum
+ * ModeEnum
  **************************************/
 
-type AdapterEnum int
+type ModeEnum int
 
 const (
-	AdapterUnknown AdapterEnum = iota
-	AdapterDelve
+	ModeUnknown ModeEnum = iota
+	ModeSequence
+	ModeExplore
 )
 
-func (t AdapterEnum) String() string {
-	return [...]string{"Unknown", "delve"}[t]
+func (t ModeEnum) String() string {
+	return [...]string{"unknown", "sequence", "explore"}[t]
 }
 
-func (t *AdapterEnum) FromString(Adapter string) AdapterEnum {
-	return map[string]AdapterEnum{
-		"Unknown": AdapterUnknown,
-		"delve":   AdapterDelve,
-	}[Adapter]
+func (t *ModeEnum) FromString(Mode string) ModeEnum {
+	return map[string]ModeEnum{
+		"unknown":  ModeUnknown,
+		"sequence": ModeSequence,
+		"explore":  ModeExplore,
+	}[Mode]
 }
 
-func (t AdapterEnum) MarshalJSON() ([]byte, error) {
+func (t ModeEnum) MarshalJSON() ([]byte, error) {
 	return json.Marshal(t.String())
 }
 
-func (t *AdapterEnum) UnmarshalJSON(b []byte) error {
+func (t *ModeEnum) UnmarshalJSON(b []byte) error {
+	var s string
+	err := json.Unmarshal(b, &s)
+	if err != nil {
+		return err
+	}
+	*t = t.FromString(s)
+	return nil
+}
+
+/**************************************
+ * InvariantTypeEnum
+ **************************************/
+
+type InvariantTypeEnum int
+
+const (
+	InvariantTypeUnknown InvariantTypeEnum = iota
+	InvariantTypeShell
+	InvariantTypeEvaluate
+)
+
+func (t InvariantTypeEnum) String() string {
+	return [...]string{"unknown", "shell", "evaluate"}[t]
+}
+
+func (t *InvariantTypeEnum) FromString(InvariantType string) InvariantTypeEnum {
+	return map[string]InvariantTypeEnum{
+		"unknown":  InvariantTypeUnknown,
+		"shell":    InvariantTypeShell,
+		"evaluate": InvariantTypeEvaluate,
+	}[InvariantType]
+}
+
+func (t InvariantTypeEnum) MarshalJSON() ([]byte, error) {
+	return json.Marshal(t.String())
+}
+
+func (t *InvariantTypeEnum) UnmarshalJSON(b []byte) error {
+	var s string
+	err := json.Unmarshal(b, &s)
+	if err != nil {
+		return err
+	}
+	*t = t.FromString(s)
+	return nil
+}
+
+/**************************************
+ * RequestEnum
+ **************************************/
+
+type RequestEnum int
+
+const (
+	RequestUnknown RequestEnum = iota
+	RequestLaunch
+	RequestAttach
+)
+
+func (t RequestEnum) String() string {
+	return [...]string{"unknown", "launch", "attach"}[t]
+}
+
+func (t *RequestEnum) FromString(Request string) RequestEnum {
+	return map[string]RequestEnum{
+		"unknown": RequestUnknown,
+		"launch":  RequestLaunch,
+		"attach":  RequestAttach,
+	}[Request]
+}
+
+func (t RequestEnum) MarshalJSON() ([]byte, error) {
+	return json.Marshal(t.String())
+}
+
+func (t *RequestEnum) UnmarshalJSON(b []byte) error {
 	var s string
 	err := json.Unmarshal(b, &s)
 	if err != nil {
@@ -0,0 +1,71 @@
+package runner
+
+import (
+	"fmt"
+	"github.com/gookit/color"
+	"github.com/weinberg/concurrencyRunner/pkg/config"
+	"strings"
+)
+
+/****************************************************
+ * Assertions
+ ***************************************************/
+
+// AssertionResult is the outcome of an assert action
+type AssertionResult struct {
+	Step       int
+	InstanceId string
+	Expression string
+	Expect     string
+	Passed     bool
+	// Actual is the value of the expression
+	Actual  string
+	Message string
+}
+
+// actionAssert evaluates an expression in the top frame of a stopped instance
+// and compares it to the expected value, or checks that it is true when no
+// value is expected. A mismatch fails the scenario but does not stop the
+// sequence, so later steps still produce evidence.
+func (r *Runtime) actionAssert(action config.Action) (err error) {
+	ia, err := r.instanceAdapter(action.InstanceId)
+	if err != nil {
+		return err
+	}
+	if action.Expression == "" {
+		return fmt.Errorf("assert requires an expression")
+	}
+
+	value, err := r.evaluate(ia, action.Expression)
+	if err != nil {
+		return err
+	}
+
+	result := AssertionResult{
+		Step:       r.Step,
+		InstanceId: action.InstanceId,
+		Expression: action.Expression,
+		Expect:     action.Expect,
+		Actual:     value,
+	}
+	if action.Expect != "" {
+		result.Passed = value == action.Expect
+		result.Message = fmt.Sprintf("%s = %s, expected %s", action.Expression, value, action.Expect)
+	} else {
+		// go and javascript print true, python prints True
+		result.Passed = strings.EqualFold(value, "true")
+		result.Message = fmt.Sprintf("%s is %s", action.Expression, value)
+	}
+	r.Assertions = append(r.Assertions, result)
+
+	status := "PASS"
+	if !result.Passed {
+		status = "FAIL"
+	}
+	printPrefix(&ia.Instance)
+	c := color.C256(247)
+	c.Printf("ACTION: ASSERT %s: %s\n", status, result.Message)
+	r.record(action, TraceEntry{Message: fmt.Sprintf("assert %s: %s", strings.ToLower(status), result.Message)})
+
+	return
+}
@@ -0,0 +1,130 @@
+package runner
+
+import (
+	"errors"
+	"fmt"
+	"github.com/google/go-dap"
+	"github.com/gookit/color"
+	"github.com/weinberg/concurrencyRunner/pkg/client"
+	"github.com/weinberg/concurrencyRunner/pkg/config"
+	"time"
+)
+
+// DEFAULT_WINDOW is the window in seconds for expectBlocked and
+// expectUnblocked when the action does not specify one
+const DEFAULT_WINDOW time.Duration = 1
+
+func actionWindow(action config.Action) time.Duration {
+	window := action.Window
+	if window == 0 {
+		window = DEFAULT_WINDOW
+	}
+	return window * time.Second
+}
+
+// actionExpectBlocked continues an instance and asserts that it neither stops
+// nor terminates within the window. The stacks of the blocked instance are
+// recorded as evidence and the instance is left running so it can proceed
+// once whatever it is waiting on is released.
+func (r *Runtime) actionExpectBlocked(action config.Action) (err error) {
+	ia, err := r.instanceAdapter(action.InstanceId)
+	if err != nil {
+		return err
+	}
+	cl := ia.Client
+
+	_, err = cl.Continue(r.ctx, ia.ThreadId)
+	if err != nil {
+		return err
+	}
+
+	window := actionWindow(action)
+	printPrefix(&ia.Instance)
+	c := color.C256(247)
+	c.Printf("ACTION: EXPECT BLOCKED for %s\n", window)
+
+	m, err := ia.Execution.NextWithTimeout(r.ctx, window)
+	if err == nil {
+		// keep the state of the instance current for later steps
+		if event, ok := m.(*dap.StoppedEvent); ok {
+			ia.ThreadId = event.Body.ThreadId
+		}
+		noteExit(ia, m)
+		return fmt.Errorf("expected instance to be blocked but it %s", describeUnblockEvent(ia, m))
+	}
+	if !errors.Is(err, client.ErrTimeout) {
+		return err
+	}
+
+	stacks, err := r.haltAndCaptureStacks(ia)
+	if err != nil {
+		return fmt.Errorf("instance is blocked but its stacks could not be captured: %w", err)
+	}
+
+	location := stacksLocation(stacks)
+	printPrefix(&ia.Instance)
+	c.Printf("BLOCKED in %s\n", location)
+	printStacks(ia, stacks)
+	r.record(action, TraceEntry{
+		Message: fmt.Sprintf("blocked in %s", location),
+		Stacks:  stacks,
+	})
+
+	// resume so the instance keeps waiting
+	_, err = cl.Continue(r.ctx, ia.ThreadId)
+	return err
+}
+
+// actionExpectUnblocked asserts that a running instance stops at a breakpoint
+// or terminates within the window. When it stops at a breakpoint the instance
+// is paused there, as after a pause action.
+func (r *Runtime) actionExpectUnblocked(action config.Action) (err error) {
+	ia, err := r.instanceAdapter(action.InstanceId)
+	if err != nil {
+		return err
+	}
+
+	window := actionWindow(action)
+	printPrefix(&ia.Instance)
+	c := color.C256(247)
+	c.Printf("ACTION: EXPECT UNBLOCKED within %s\n", window)
+
+	m, err := ia.Execution.NextWithTimeout(r.ctx, window)
+	if errors.Is(err, client.ErrTimeout) {
+		return r.diagnoseTimeout(ia, "become unblocked", window)
+	}
+	if err != nil {
+		return err
+	}
+
+	if event, ok := m.(*dap.StoppedEvent); ok {
+		ia.ThreadId = event.Body.ThreadId
+	}
+	noteExit(ia, m)
+
+	message := describeUnblockEvent(ia, m)
+	printPrefix(&ia.Instance)
+	c.Printf("UNBLOCKED, %s\n", message)
+	r.record(action, TraceEntry{Message: message})
+
+	return
+}
+
+// describeUnblockEvent describes the event which shows an instance is no
+// longer blocked
+func describeUnblockEvent(ia *InstanceAdapter, m dap.Message) string {
+	switch event := m.(type) {
+	case *dap.StoppedEvent:
+		for _, id := range event.Body.HitBreakpointIds {
+			bp := ia.Breakpoints[id]
+			return fmt.Sprintf("stopped at file '%s', line: %d", bp.Source.Path, bp.Line)
+		}
+		return fmt.Sprintf("stopped (%s)", event.Body.Reason)
+	case *dap.ExitedEvent:
+		return fmt.Sprintf("exited with code %d", event.Body.ExitCode)
+	case *dap.TerminatedEvent:
+		return "terminated"
+	default:
+		return fmt.Sprintf("sent %T", m)
+	}
+}
@@ -0,0 +1,121 @@
+package runner
+
+import (
+	"fmt"
+	"github.com/google/go-dap"
+	"github.com/gookit/color"
+	"github.com/weinberg/concurrencyRunner/pkg/config"
+	"strings"
+)
+
+/****************************************************
+ * Variable capture
+ ***************************************************/
+
+// Capture is the value of an expression in a stopped instance
+type Capture struct {
+	Expression string
+	Value      string
+	// Error is set instead of Value when the expression could not be resolved
+	Error string
+}
+
+func (c Capture) String() string {
+	if c.Error != "" {
+		return fmt.Sprintf("%s: %s", c.Expression, c.Error)
+	}
+	return fmt.Sprintf("%s=%s", c.Expression, c.Value)
+}
+
+// topFrameId returns the id of the top stack frame of a stopped instance
+func (r *Runtime) topFrameId(ia *InstanceAdapter) (int, error) {
+	stackTrace, err := ia.Client.StackTrace(r.ctx, ia.ThreadId, 0, 1)
+	if err != nil {
+		return 0, err
+	}
+	if len(stackTrace.Body.StackFrames) == 0 {
+		return 0, fmt.Errorf("instance '%s' has no stack frames", ia.Instance.Name)
+	}
+	return stackTrace.Body.StackFrames[0].Id, nil
+}
+
+// captureValues resolves the action's capture list in the top frame of a
+// stopped instance, then prints and records the values. Variable names are
+// looked up in the frame's scopes and anything else is evaluated. An
+// expression which cannot be resolved is recorded with its error rather than
+// failing the action.
+func (r *Runtime) captureValues(ia *InstanceAdapter, action config.Action) error {
+	if len(action.Capture) == 0 {
+		return nil
+	}
+	cl := ia.Client
+
+	frameId, err := r.topFrameId(ia)
+	if err != nil {
+		return err
+	}
+	variables, err := r.frameVariables(ia, frameId)
+	if err != nil {
+		return err
+	}
+
+	captures := make([]Capture, 0, len(action.Capture))
+	descriptions := make([]string, 0, len(action.Capture))
+	for _, expression := range action.Capture {
+		capture := Capture{Expression: expression}
+		if variable, ok := variables[expression]; ok {
+			capture.Value = variable.Value
+		} else if evaluateResponse, err := cl.Evaluate(r.ctx, expression, frameId, "watch"); err != nil {
+			capture.Error = err.Error()
+		} else {
+			capture.Value = evaluateResponse.Body.Result
+		}
+
+		printPrefix(&ia.Instance)
+		c := color.C256(247)
+		c.Printf("CAPTURE %s\n", capture)
+		captures = append(captures, capture)
+		descriptions = append(descriptions, capture.String())
+	}
+
+	r.record(action, TraceEntry{
+		Message:  fmt.Sprintf("captured %s", strings.Join(descriptions, ", ")),
+		Captures: captures,
+	})
+	return nil
+}
+
+// frameVariable is a variable in one of the scopes of a frame
+type frameVariable struct {
+	dap.Variable
+	// Scope is the variablesReference of the scope holding the variable
+	Scope int
+}
+
+// frameVariables returns the variables in the scopes of a frame by name.
+// Expensive scopes, such as globals, are skipped. Where a name occurs in
+// several scopes the innermost one wins.
+func (r *Runtime) frameVariables(ia *InstanceAdapter, frameId int) (map[string]frameVariable, error) {
+	cl := ia.Client
+	scopes, err := cl.Scopes(r.ctx, frameId)
+	if err != nil {
+		return nil, err
+	}
+
+	variables := make(map[string]frameVariable)
+	for _, scope := range scopes.Body.Scopes {
+		if scope.Expensive || scope.VariablesReference == 0 {
+			continue
+		}
+		variablesResponse, err := cl.Variables(r.ctx, scope.VariablesReference)
+		if err != nil {
+			return nil, err
+		}
+		for _, variable := range variablesResponse.Body.Variables {
+			if _, ok := variables[variable.Name]; !ok {
+				variables[variable.Name] = frameVariable{Variable: variable, Scope: scope.VariablesReference}
+			}
+		}
+	}
+	return variables, nil
+}
@@ -0,0 +1,96 @@
+package runner
+
+import (
+	"fmt"
+	"github.com/google/go-dap"
+	"github.com/gookit/color"
+	"time"
+)
+
+// DIAGNOSTIC_TIMEOUT is how long to wait for an instance to halt after a
+// pause request
+const DIAGNOSTIC_TIMEOUT time.Duration = 5 * time.Second
+
+// STACK_DEPTH is the number of frames captured per thread
+const STACK_DEPTH int = 10
+
+// ThreadStack is the stack of a single thread (goroutine for delve)
+type ThreadStack struct {
+	Thread dap.Thread
+	Frames []dap.StackFrame
+}
+
+// haltAndCaptureStacks pauses a running instance and captures the stack of
+// every thread. The thread which the adapter reports as stopped is first.
+func (r *Runtime) haltAndCaptureStacks(ia *InstanceAdapter) (stacks []ThreadStack, err error) {
+	cl := ia.Client
+
+	_, err = cl.Pause(r.ctx, ia.ThreadId)
+	if err != nil {
+		return nil, err
+	}
+	event, err := r.waitStopped(ia, DIAGNOSTIC_TIMEOUT)
+	if err != nil {
+		return nil, fmt.Errorf("instance '%s' did not halt: %w", ia.Instance.Name, err)
+	}
+	ia.ThreadId = event.Body.ThreadId
+
+	return r.captureStacks(ia)
+}
+
+// captureStacks returns the stack of every thread of a stopped instance
+func (r *Runtime) captureStacks(ia *InstanceAdapter) (stacks []ThreadStack, err error) {
+	cl := ia.Client
+
+	threads, err := cl.Threads(r.ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, thread := range threads.Body.Threads {
+		stackTrace, err := cl.StackTrace(r.ctx, thread.Id, 0, STACK_DEPTH)
+		if err != nil {
+			return nil, err
+		}
+
+		stack := ThreadStack{Thread: thread, Frames: stackTrace.Body.StackFrames}
+		if thread.Id == ia.ThreadId {
+			stacks = append([]ThreadStack{stack}, stacks...)
+		} else {
+			stacks = append(stacks, stack)
+		}
+	}
+
+	return
+}
+
+// location returns "function (file:line)" for the top frame of the stack
+func (s ThreadStack) location() string {
+	if len(s.Frames) == 0 {
+		return s.Thread.Name
+	}
+	frame := s.Frames[0]
+	return fmt.Sprintf("%s (%s:%d)", frame.Name, frame.Source.Path, frame.Line)
+}
+
+// stacksLocation returns the location of the stopped thread
+func stacksLocation(stacks []ThreadStack) string {
+	if len(stacks) == 0 {
+		return "unknown location"
+	}
+	return stacks[0].location()
+}
+
+func printStacks(ia *InstanceAdapter, stacks []ThreadStack) {
+	c := color.C256(247)
+	for _, stack := range stacks {
+		printPrefix(&ia.Instance)
+		c.Printf("THREAD %s\n", stack.Thread.Name)
+		for _, frame := range stack.Frames {
+			printPrefix(&ia.Instance)
+			c.Printf("    %s\n", frame.Name)
+			printPrefix(&ia.Instance)
+			c.Printf("        %s:%d\n", frame.Source.Path, frame.Line)
+		}
+	}
+}
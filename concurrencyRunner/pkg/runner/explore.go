@@ -0,0 +1,151 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/gookit/color"
+	"github.com/weinberg/concurrencyRunner/pkg/config"
+	"strings"
+)
+
+/****************************************************
+ * Explore
+ ***************************************************/
+
+// Explore runs every distinct interleaving of the instances' pause points.
+// Each schedule is run as its own scenario with freshly launched instances.
+func Explore(ctx context.Context, c *config.Config) (verdict *Verdict, err error) {
+	schedules := Schedules(c)
+
+	verdict = &Verdict{}
+	for i, schedule := range schedules {
+		description := describeSchedule(schedule)
+		printPrefix(nil)
+		cl := color.C256(247)
+		cl.Printf("SCHEDULE %d/%d: %s\n", i+1, len(schedules), description)
+
+		name := fmt.Sprintf("schedule %d: %s", i+1, description)
+		scenario := *c
+		scenario.Sequence = schedule
+		result, err := RunScenario(ctx, &scenario, name)
+		if ctx.Err() != nil {
+			// interrupted
+			return nil, fmt.Errorf("schedule %d: %w", i+1, ctx.Err())
+		}
+		if err != nil {
+			// one failing schedule must not hide the verdicts of the others
+			result = &ScenarioResult{Name: name, Err: err}
+		}
+		if result.Err != nil {
+			var timeout *TimeoutError
+			if errors.As(result.Err, &timeout) {
+				// an instance could not reach its next point because another
+				// instance is paused while holding what it needs. The schedule
+				// cannot happen, which is not a failure.
+				result.Deadlocked = true
+				printPrefix(nil)
+				cl.Printf("SCHEDULE %d deadlocked: %s\n", i+1, result.Err)
+			} else {
+				printPrefix(nil)
+				cl.Printf("SCHEDULE %d failed: %s\n", i+1, result.Err)
+			}
+		}
+		verdict.Scenarios = append(verdict.Scenarios, result)
+	}
+
+	return
+}
+
+// Schedules generates a sequence for every distinct interleaving of the
+// instances' pause points.
+//
+// An instance with n pause points executes n+1 segments: a run (or continue)
+// to each pause point followed by a final continue and wait for it to exit.
+// The segments of each instance stay in order while the segments of different
+// instances are interleaved in every possible way. Generation stops after
+// MaxSchedules schedules when it is set.
+func Schedules(c *config.Config) (schedules [][]config.Action) {
+	remaining := make([]int, len(c.Instances))
+	for i, instance := range c.Instances {
+		remaining[i] = len(instance.PausePoints) + 1
+	}
+
+	max := c.Explore.MaxSchedules
+	var generate func(prefix []config.Action)
+	generate = func(prefix []config.Action) {
+		done := true
+		for i, instance := range c.Instances {
+			if remaining[i] == 0 {
+				continue
+			}
+			done = false
+			if max > 0 && len(schedules) >= max {
+				return
+			}
+
+			segment := len(instance.PausePoints) + 1 - remaining[i]
+			remaining[i]--
+			generate(appendSegment(prefix, instance, segment))
+			remaining[i]++
+		}
+
+		if done {
+			schedules = append(schedules, prefix)
+		}
+	}
+	generate(nil)
+
+	return
+}
+
+// appendSegment returns a copy of actions with the actions for the given
+// segment of instance appended
+func appendSegment(actions []config.Action, instance config.Instance, segment int) []config.Action {
+	result := append([]config.Action{}, actions...)
+
+	actionType := config.ActionTypeContinue
+	if segment == 0 {
+		actionType = config.ActionTypeRun
+	}
+	result = append(result, config.Action{
+		InstanceId: instance.Id,
+		Type:       actionType,
+	})
+
+	if segment < len(instance.PausePoints) {
+		pausePoint := instance.PausePoints[segment]
+		result = append(result, config.Action{
+			InstanceId:    instance.Id,
+			Type:          config.ActionTypePause,
+			File:          pausePoint.File,
+			TargetComment: pausePoint.TargetComment,
+			Condition:     pausePoint.Condition,
+			HitCondition:  pausePoint.HitCondition,
+		})
+	} else {
+		// the last segment must be complete before the next segment of
+		// another instance starts
+		result = append(result, config.Action{
+			InstanceId: instance.Id,
+			Type:       config.ActionTypeWaitExit,
+		})
+	}
+
+	return result
+}
+
+// describeSchedule returns a one line summary of a schedule, e.g.
+// "1:run 1:pause(CL_PAUSE_1) 2:run 1:continue"
+func describeSchedule(schedule []config.Action) string {
+	var steps []string
+	for _, action := range schedule {
+		switch action.Type {
+		case config.ActionTypePause:
+			steps = append(steps, fmt.Sprintf("%s:%s(%s)", action.InstanceId, action.Type, action.TargetComment))
+		default:
+			steps = append(steps, fmt.Sprintf("%s:%s", action.InstanceId, action.Type))
+		}
+	}
+	return strings.Join(steps, " ")
+}
@@ -0,0 +1,130 @@
+package runner
+
+import (
+	"github.com/weinberg/concurrencyRunner/pkg/config"
+	"reflect"
+	"testing"
+)
+
+func instanceWithPausePoints(id string, targetComments ...string) config.Instance {
+	instance := config.Instance{Id: id}
+	for _, targetComment := range targetComments {
+		instance.PausePoints = append(instance.PausePoints, config.PausePoint{
+			File:          "main.go",
+			TargetComment: targetComment,
+		})
+	}
+	return instance
+}
+
+func TestSchedules(t *testing.T) {
+	tests := []struct {
+		name      string
+		instances []config.Instance
+		max       int
+		want      []string
+	}{
+		{
+			name: "2x2",
+			instances: []config.Instance{
+				instanceWithPausePoints("1", "A"),
+				instanceWithPausePoints("2", "B"),
+			},
+			want: []string{
+				"1:run 1:pause(A) 1:continue 1:waitExit 2:run 2:pause(B) 2:continue 2:waitExit",
+				"1:run 1:pause(A) 2:run 2:pause(B) 1:continue 1:waitExit 2:continue 2:waitExit",
+				"1:run 1:pause(A) 2:run 2:pause(B) 2:continue 2:waitExit 1:continue 1:waitExit",
+				"2:run 2:pause(B) 1:run 1:pause(A) 1:continue 1:waitExit 2:continue 2:waitExit",
+				"2:run 2:pause(B) 1:run 1:pause(A) 2:continue 2:waitExit 1:continue 1:waitExit",
+				"2:run 2:pause(B) 2:continue 2:waitExit 1:run 1:pause(A) 1:continue 1:waitExit",
+			},
+		},
+		{
+			name: "3x1",
+			instances: []config.Instance{
+				instanceWithPausePoints("1", "A", "B"),
+				instanceWithPausePoints("2"),
+			},
+			want: []string{
+				"1:run 1:pause(A) 1:continue 1:pause(B) 1:continue 1:waitExit 2:run 2:waitExit",
+				"1:run 1:pause(A) 1:continue 1:pause(B) 2:run 2:waitExit 1:continue 1:waitExit",
+				"1:run 1:pause(A) 2:run 2:waitExit 1:continue 1:pause(B) 1:continue 1:waitExit",
+				"2:run 2:waitExit 1:run 1:pause(A) 1:continue 1:pause(B) 1:continue 1:waitExit",
+			},
+		},
+		{
+			name: "max schedules",
+			instances: []config.Instance{
+				instanceWithPausePoints("1", "A"),
+				instanceWithPausePoints("2", "B"),
+			},
+			max: 2,
+			want: []string{
+				"1:run 1:pause(A) 1:continue 1:waitExit 2:run 2:pause(B) 2:continue 2:waitExit",
+				"1:run 1:pause(A) 2:run 2:pause(B) 1:continue 1:waitExit 2:continue 2:waitExit",
+			},
+		},
+		{
+			// far too many interleavings to build, generation must stop at
+			// the limit
+			name: "max schedules of many pause points",
+			instances: []config.Instance{
+				instanceWithPausePoints("1", "A", "B", "C", "D", "E"),
+				instanceWithPausePoints("2", "A", "B", "C", "D", "E"),
+				instanceWithPausePoints("3", "A", "B", "C", "D", "E"),
+				instanceWithPausePoints("4", "A", "B", "C", "D", "E"),
+			},
+			max: 1,
+			want: []string{
+				"1:run 1:pause(A) 1:continue 1:pause(B) 1:continue 1:pause(C) 1:continue 1:pause(D) 1:continue 1:pause(E) 1:continue 1:waitExit " +
+					"2:run 2:pause(A) 2:continue 2:pause(B) 2:continue 2:pause(C) 2:continue 2:pause(D) 2:continue 2:pause(E) 2:continue 2:waitExit " +
+					"3:run 3:pause(A) 3:continue 3:pause(B) 3:continue 3:pause(C) 3:continue 3:pause(D) 3:continue 3:pause(E) 3:continue 3:waitExit " +
+					"4:run 4:pause(A) 4:continue 4:pause(B) 4:continue 4:pause(C) 4:continue 4:pause(D) 4:continue 4:pause(E) 4:continue 4:waitExit",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &config.Config{
+				Instances: tt.instances,
+				Explore:   config.ExploreOptions{MaxSchedules: tt.max},
+			}
+			schedules := Schedules(c)
+
+			var got []string
+			for _, schedule := range schedules {
+				got = append(got, describeSchedule(schedule))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got schedules\n%q\nwant\n%q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAppendSegment(t *testing.T) {
+	instance := instanceWithPausePoints("1", "A")
+	instance.PausePoints[0].Condition = "i == 2"
+
+	first := appendSegment(nil, instance, 0)
+	want := []config.Action{
+		{InstanceId: "1", Type: config.ActionTypeRun},
+		{InstanceId: "1", Type: config.ActionTypePause, File: "main.go", TargetComment: "A", Condition: "i == 2"},
+	}
+	if !reflect.DeepEqual(first, want) {
+		t.Fatalf("segment 0: got %+v, want %+v", first, want)
+	}
+
+	second := appendSegment(first, instance, 1)
+	want = append(want,
+		config.Action{InstanceId: "1", Type: config.ActionTypeContinue},
+		config.Action{InstanceId: "1", Type: config.ActionTypeWaitExit},
+	)
+	if !reflect.DeepEqual(second, want) {
+		t.Fatalf("segment 1: got %+v, want %+v", second, want)
+	}
+	if len(first) != 2 {
+		t.Errorf("appendSegment modified its input")
+	}
+}
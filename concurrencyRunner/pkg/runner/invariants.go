@@ -0,0 +1,118 @@
+package runner
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/weinberg/concurrencyRunner/pkg/config"
+	"os/exec"
+	"regexp"
+	"strconv"
+)
+
+/****************************************************
+ * Invariants
+ ***************************************************/
+
+// CheckInvariants checks every invariant in the config and returns the results
+func (r *Runtime) CheckInvariants(c *config.Config) (results []InvariantResult) {
+	for _, invariant := range c.Invariants {
+		var result InvariantResult
+		switch invariant.Type {
+		case config.InvariantTypeShell:
+			result = r.checkShellInvariant(invariant)
+		case config.InvariantTypeEvaluate:
+			result = r.checkEvaluateInvariant(invariant)
+		default:
+			result = InvariantResult{
+				Invariant: invariant,
+				Message:   fmt.Sprintf("unknown invariant type '%s'", invariant.Type),
+			}
+		}
+		printInvariantResult(result)
+		results = append(results, result)
+	}
+	return
+}
+
+// checkShellInvariant runs the invariant's command with `sh -c` and compares
+// the exit code and output
+func (r *Runtime) checkShellInvariant(invariant config.Invariant) (result InvariantResult) {
+	result.Invariant = invariant
+
+	cmd := exec.Command("sh", "-c", invariant.Command)
+	cmd.Dir = invariant.Cwd
+	output, err := cmd.CombinedOutput()
+	exitCode := 0
+	if err != nil {
+		var exitErr *exec.ExitError
+		if !errors.As(err, &exitErr) {
+			result.Message = fmt.Sprintf("cannot run command: %s", err)
+			return
+		}
+		exitCode = exitErr.ExitCode()
+	}
+	result.Actual = string(bytes.TrimSpace(output))
+
+	if exitCode != invariant.ExpectExitCode {
+		result.Message = fmt.Sprintf("expected exit code %d, got %d: %s", invariant.ExpectExitCode, exitCode, result.Actual)
+		return
+	}
+
+	if invariant.ExpectOutput != "" {
+		re, err := regexp.Compile(invariant.ExpectOutput)
+		if err != nil {
+			result.Message = fmt.Sprintf("invalid expectOutput: %s", err)
+			return
+		}
+		if !re.MatchString(result.Actual) {
+			result.Message = fmt.Sprintf("output %s does not match '%s'", strconv.Quote(result.Actual), invariant.ExpectOutput)
+			return
+		}
+	}
+
+	result.Passed = true
+	return
+}
+
+// checkEvaluateInvariant evaluates the invariant's expression in the top frame
+// of a paused instance and compares the result
+func (r *Runtime) checkEvaluateInvariant(invariant config.Invariant) (result InvariantResult) {
+	result.Invariant = invariant
+
+	ia, ok := r.InstanceAdapters[invariant.InstanceId]
+	if !ok {
+		result.Message = fmt.Sprintf("unknown instance '%s'", invariant.InstanceId)
+		return
+	}
+
+	value, err := r.evaluate(ia, invariant.Expression)
+	if err != nil {
+		result.Message = err.Error()
+		return
+	}
+	result.Actual = value
+
+	if value != invariant.Expect {
+		result.Message = fmt.Sprintf("%s = %s, expected %s", invariant.Expression, value, invariant.Expect)
+		return
+	}
+
+	result.Passed = true
+	return
+}
+
+// evaluate evaluates expression in the top stack frame of a stopped instance
+func (r *Runtime) evaluate(ia *InstanceAdapter, expression string) (string, error) {
+	frameId, err := r.topFrameId(ia)
+	if err != nil {
+		return "", err
+	}
+
+	evaluateResponse, err := ia.Client.Evaluate(r.ctx, expression, frameId, "watch")
+	if err != nil {
+		return "", err
+	}
+
+	return evaluateResponse.Body.Result, nil
+}
@@ -0,0 +1,298 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/google/go-dap"
+	"github.com/gookit/color"
+	"github.com/weinberg/concurrencyRunner/pkg/client"
+	"github.com/weinberg/concurrencyRunner/pkg/config"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// errOutputEnded is returned when output ends while waiting for a line
+var errOutputEnded = errors.New("output ended")
+
+/****************************************************
+ * Program output
+ ***************************************************/
+
+// OutputLine is a line written by an instance's program
+type OutputLine struct {
+	// Category is the DAP output category: stdout, stderr or console. Lines
+	// printed by logpoints have the category logpoint.
+	Category string
+	Text     string
+}
+
+// LogpointHit is a message printed by a logpoint
+type LogpointHit struct {
+	// Location is the path and line of the logpoint
+	Location string
+	Message  string
+	// Time is when the message arrived
+	Time time.Time
+}
+
+// OutputStream collects the program output of an instance from DAP output
+// events. Output is split into lines which are printed with the instance
+// prefix, written to the instance's log file if it has one and kept so the
+// runner can make assertions about them.
+type OutputStream struct {
+	ia  *InstanceAdapter
+	sub *client.Subscription
+	log *os.File
+
+	mu    sync.Mutex
+	lines []OutputLine
+	// partial holds the incomplete last line of each category
+	partial map[string]string
+	// next is the index of the first line not yet consumed by WaitFor
+	next int
+	// logpoints are the locations of the instance's logpoints, hits are the
+	// messages they printed which have not been taken yet
+	logpoints map[string]bool
+	hits      []LogpointHit
+	notify    chan struct{}
+	done      chan struct{}
+}
+
+// newOutputStream subscribes to the output events of ia and starts collecting
+// them
+func newOutputStream(ctx context.Context, ia *InstanceAdapter) (*OutputStream, error) {
+	s := &OutputStream{
+		ia:      ia,
+		partial: make(map[string]string),
+		notify:  make(chan struct{}, 1),
+		done:    make(chan struct{}),
+	}
+
+	if ia.Instance.LogFile != "" {
+		// append so the output of earlier explore schedules is kept
+		log, err := os.OpenFile(ia.Instance.LogFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+		if err != nil {
+			return nil, fmt.Errorf("cannot open log file for instance '%s': %w", ia.Instance.Name, err)
+		}
+		s.log = log
+	}
+
+	s.sub = ia.Client.Subscribe(client.OutputCategories("stdout", "stderr", "console"))
+	go s.run(ctx)
+
+	return s, nil
+}
+
+// Lines returns the complete lines written so far
+func (s *OutputStream) Lines() []OutputLine {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]OutputLine(nil), s.lines...)
+}
+
+// Close waits for the output to be collected, which ends when the client's
+// connection closes, and closes the log file
+func (s *OutputStream) Close() error {
+	<-s.done
+	if s.log != nil {
+		return s.log.Close()
+	}
+	return nil
+}
+
+// WaitFor returns the first line matching pattern which was not consumed by an
+// earlier WaitFor. Lines written before the call are included, so a line
+// printed just before waiting is not missed.
+func (s *OutputStream) WaitFor(ctx context.Context, pattern *regexp.Regexp, timeout time.Duration) (OutputLine, error) {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	for {
+		if line, ok := s.match(pattern); ok {
+			return line, nil
+		}
+		select {
+		case <-s.notify:
+		case <-s.done:
+			if line, ok := s.match(pattern); ok {
+				return line, nil
+			}
+			return OutputLine{}, errOutputEnded
+		case <-timer.C:
+			return OutputLine{}, client.ErrTimeout
+		case <-ctx.Done():
+			return OutputLine{}, ctx.Err()
+		}
+	}
+}
+
+// match consumes the lines up to and including the first match of pattern
+func (s *OutputStream) match(pattern *regexp.Regexp) (OutputLine, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := s.next; i < len(s.lines); i++ {
+		if pattern.MatchString(s.lines[i].Text) {
+			s.next = i + 1
+			return s.lines[i], true
+		}
+	}
+	return OutputLine{}, false
+}
+
+func (s *OutputStream) run(ctx context.Context) {
+	defer close(s.done)
+	for {
+		m, err := s.sub.Next(ctx)
+		if err != nil {
+			break
+		}
+		event := m.(*dap.OutputEvent)
+		if location, ok := s.logpointLocation(event); ok {
+			s.writeLogpoint(location, event.Body.Output)
+			continue
+		}
+		category := event.Body.Category
+		if category == "" {
+			category = "console"
+		}
+		s.write(category, event.Body.Output)
+	}
+
+	// output without a trailing newline
+	for category, text := range s.partial {
+		if text != "" {
+			s.add(OutputLine{Category: category, Text: text})
+		}
+	}
+}
+
+// logpointLocation returns the location of the logpoint which printed event.
+// Adapters send the source and line of the logpoint with its output. Output
+// from adapters which do not is treated as program output.
+func (s *OutputStream) logpointLocation(event *dap.OutputEvent) (string, bool) {
+	if event.Body.Source.Path == "" || event.Body.Line == 0 {
+		return "", false
+	}
+	location := logpointLocation(event.Body.Source.Path, event.Body.Line)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return location, s.logpoints[location]
+}
+
+// writeLogpoint adds the lines of a logpoint message to the output and keeps
+// the message for the trace
+func (s *OutputStream) writeLogpoint(location, output string) {
+	message := strings.TrimRight(output, "\r\n")
+	s.mu.Lock()
+	s.hits = append(s.hits, LogpointHit{Location: location, Message: message, Time: time.Now()})
+	s.mu.Unlock()
+
+	for _, line := range strings.Split(message, "\n") {
+		s.add(OutputLine{Category: "logpoint", Text: strings.TrimSuffix(line, "\r")})
+	}
+}
+
+// setLogpoints sets the locations of the instance's logpoints
+func (s *OutputStream) setLogpoints(locations []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.logpoints = make(map[string]bool)
+	for _, location := range locations {
+		s.logpoints[location] = true
+	}
+}
+
+// takeLogpointHits returns the logpoint messages printed since the last call
+func (s *OutputStream) takeLogpointHits() []LogpointHit {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	hits := s.hits
+	s.hits = nil
+	return hits
+}
+
+// logpointLocation identifies a logpoint by its cleaned absolute path and
+// line. Adapters do not always report the path in the form it was set with,
+// so symlinks are resolved where possible.
+func logpointLocation(file string, line int) string {
+	if abs, err := filepath.Abs(file); err == nil {
+		file = abs
+	}
+	if resolved, err := filepath.EvalSymlinks(file); err == nil {
+		file = resolved
+	}
+	return fmt.Sprintf("%s:%d", file, line)
+}
+
+// write splits output into lines. The last line is held back until it is
+// completed by a later event.
+func (s *OutputStream) write(category, output string) {
+	text := s.partial[category] + output
+	lines := strings.Split(text, "\n")
+	s.partial[category] = lines[len(lines)-1]
+
+	for _, line := range lines[:len(lines)-1] {
+		line = strings.TrimSuffix(line, "\r")
+		if category == "console" && s.ia.Adapter.IsChatter(line) {
+			continue
+		}
+		s.add(OutputLine{Category: category, Text: line})
+	}
+}
+
+func (s *OutputStream) add(line OutputLine) {
+	s.mu.Lock()
+	s.lines = append(s.lines, line)
+	s.mu.Unlock()
+	select {
+	case s.notify <- struct{}{}:
+	default:
+	}
+
+	c := color.C256(247)
+	fmt.Printf("%s%s%s\n", getPrefix(&s.ia.Instance), c.Sprintf("%s: ", strings.ToUpper(line.Category)), line.Text)
+
+	if s.log != nil {
+		fmt.Fprintln(s.log, line.Text)
+	}
+}
+
+// actionWaitForOutput waits until the instance prints a line matching the
+// action's pattern
+func (r *Runtime) actionWaitForOutput(action config.Action) (err error) {
+	ia, err := r.instanceAdapter(action.InstanceId)
+	if err != nil {
+		return err
+	}
+	pattern, err := regexp.Compile(action.Pattern)
+	if err != nil {
+		return fmt.Errorf("invalid pattern '%s': %w", action.Pattern, err)
+	}
+
+	timeout := r.actionTimeout(action)
+	printPrefix(&ia.Instance)
+	c := color.C256(247)
+	c.Printf("ACTION: WAIT FOR OUTPUT matching '%s'\n", action.Pattern)
+
+	line, err := ia.Output.WaitFor(r.ctx, pattern, timeout)
+	if errors.Is(err, client.ErrTimeout) {
+		return r.diagnoseTimeout(ia, fmt.Sprintf("print a line matching '%s'", action.Pattern), timeout)
+	}
+	if errors.Is(err, errOutputEnded) {
+		return fmt.Errorf("output ended without a line matching '%s'", action.Pattern)
+	}
+	if err != nil {
+		return err
+	}
+
+	printPrefix(&ia.Instance)
+	c.Printf("MATCHED %s: %s\n", strings.ToUpper(line.Category), line.Text)
+	r.record(action, TraceEntry{Message: fmt.Sprintf("printed '%s'", line.Text)})
+
+	return
+}
@@ -0,0 +1,261 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"github.com/google/go-dap"
+	"github.com/weinberg/concurrencyRunner/pkg/adapter"
+	"github.com/weinberg/concurrencyRunner/pkg/client"
+	"github.com/weinberg/concurrencyRunner/pkg/config"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"testing"
+	"time"
+)
+
+// fakeAdapter sends output events to an OutputStream over a client connection
+type fakeAdapter struct {
+	t      *testing.T
+	writer *io.PipeWriter
+	seq    int
+}
+
+func (f *fakeAdapter) output(category, output string) {
+	f.seq++
+	event := &dap.OutputEvent{}
+	event.Type = "event"
+	event.Event.Event = "output"
+	event.Seq = f.seq
+	event.Body.Category = category
+	event.Body.Output = output
+	err := dap.WriteProtocolMessage(f.writer, event)
+	if err != nil {
+		f.t.Error(err)
+	}
+}
+
+// logpoint sends the output of a logpoint at file and line
+func (f *fakeAdapter) logpoint(file string, line int, output string) {
+	f.seq++
+	event := &dap.OutputEvent{}
+	event.Type = "event"
+	event.Event.Event = "output"
+	event.Seq = f.seq
+	event.Body.Category = "console"
+	event.Body.Output = output
+	event.Body.Source.Path = file
+	event.Body.Line = line
+	err := dap.WriteProtocolMessage(f.writer, event)
+	if err != nil {
+		f.t.Error(err)
+	}
+}
+
+// close ends the connection, which ends the output
+func (f *fakeAdapter) close() {
+	f.writer.Close()
+}
+
+func newTestOutputStream(t *testing.T) (*OutputStream, *fakeAdapter) {
+	return newTestOutputStreamWithLog(t, "")
+}
+
+func newTestOutputStreamWithLog(t *testing.T, logFile string) (*OutputStream, *fakeAdapter) {
+	ad, err := adapter.New("delve")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	clientReader, adapterWriter := io.Pipe()
+	_, clientWriter := io.Pipe()
+	cl := client.NewClientFromPipes(clientReader, clientWriter)
+	go client.ReadMessageLoop(cl)
+	t.Cleanup(cl.Close)
+
+	ia := &InstanceAdapter{
+		Client:   cl,
+		Adapter:  ad,
+		Instance: config.Instance{Id: "1", Name: "test", LogFile: logFile},
+	}
+	s, err := newOutputStream(context.Background(), ia)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s, &fakeAdapter{t: t, writer: adapterWriter}
+}
+
+func TestOutputStreamLines(t *testing.T) {
+	tests := []struct {
+		name   string
+		events [][2]string
+		want   []OutputLine
+	}{
+		{
+			name: "line split across events",
+			events: [][2]string{
+				{"stdout", "hel"},
+				{"stdout", "lo\nwor"},
+				{"stdout", "ld\n"},
+			},
+			want: []OutputLine{
+				{Category: "stdout", Text: "hello"},
+				{Category: "stdout", Text: "world"},
+			},
+		},
+		{
+			name: "crlf",
+			events: [][2]string{
+				{"stdout", "one\r\ntwo\r"},
+				{"stdout", "\n"},
+			},
+			want: []OutputLine{
+				{Category: "stdout", Text: "one"},
+				{Category: "stdout", Text: "two"},
+			},
+		},
+		{
+			name: "interleaved stdout and stderr",
+			events: [][2]string{
+				{"stdout", "out "},
+				{"stderr", "err "},
+				{"stdout", "line\n"},
+				{"stderr", "line\n"},
+			},
+			want: []OutputLine{
+				{Category: "stdout", Text: "out line"},
+				{Category: "stderr", Text: "err line"},
+			},
+		},
+		{
+			name: "adapter chatter and unterminated last line",
+			events: [][2]string{
+				{"console", "DAP server listening at: 127.0.0.1:1234\n"},
+				{"stdout", "no newline"},
+			},
+			want: []OutputLine{
+				{Category: "stdout", Text: "no newline"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, fake := newTestOutputStream(t)
+			for _, event := range tt.events {
+				fake.output(event[0], event[1])
+			}
+			fake.close()
+			err := s.Close()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := s.Lines()
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got lines %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOutputStreamWaitFor(t *testing.T) {
+	s, fake := newTestOutputStream(t)
+	ctx := context.Background()
+	pattern := regexp.MustCompile("^match")
+
+	// lines written before WaitFor is called are found
+	fake.output("stdout", "match 1\nskip\n")
+	fake.output("stderr", "match 2\n")
+
+	for _, want := range []OutputLine{
+		{Category: "stdout", Text: "match 1"},
+		{Category: "stderr", Text: "match 2"},
+	} {
+		line, err := s.WaitFor(ctx, pattern, time.Second)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if line != want {
+			t.Errorf("got %+v, want %+v", line, want)
+		}
+	}
+
+	// matched lines are consumed
+	_, err := s.WaitFor(ctx, pattern, 50*time.Millisecond)
+	if !errors.Is(err, client.ErrTimeout) {
+		t.Errorf("got %v, want %v", err, client.ErrTimeout)
+	}
+
+	// a line completed while waiting is found
+	fake.output("stdout", "mat")
+	go fake.output("stdout", "ch 3\n")
+	line, err := s.WaitFor(ctx, pattern, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if line.Text != "match 3" {
+		t.Errorf("got %+v, want match 3", line)
+	}
+
+	fake.close()
+	_, err = s.WaitFor(ctx, pattern, time.Second)
+	if !errors.Is(err, errOutputEnded) {
+		t.Errorf("got %v, want %v", err, errOutputEnded)
+	}
+}
+
+func TestOutputStreamLogFileAppends(t *testing.T) {
+	logFile := filepath.Join(t.TempDir(), "instance.log")
+
+	// one stream per explore schedule
+	for _, output := range []string{"schedule 1\n", "schedule 2\n"} {
+		s, fake := newTestOutputStreamWithLog(t, logFile)
+		fake.output("stdout", output)
+		fake.close()
+		err := s.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	content, err := os.ReadFile(logFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "schedule 1\nschedule 2\n"
+	if string(content) != want {
+		t.Errorf("got log %q, want %q", content, want)
+	}
+}
+
+func TestOutputStreamLogpointsInFilesWithTheSameName(t *testing.T) {
+	dir := t.TempDir()
+	first := filepath.Join(dir, "first", "main.go")
+	second := filepath.Join(dir, "second", "main.go")
+
+	s, fake := newTestOutputStream(t)
+	s.setLogpoints([]string{logpointLocation(first, 10)})
+
+	fake.logpoint(second, 10, "not a logpoint\n")
+	fake.logpoint(first, 10, "hit\n")
+	fake.close()
+	err := s.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hits := s.takeLogpointHits()
+	if len(hits) != 1 || hits[0].Message != "hit" || hits[0].Location != logpointLocation(first, 10) {
+		t.Errorf("got hits %+v, want only the hit in %s", hits, first)
+	}
+	want := []OutputLine{
+		{Category: "console", Text: "not a logpoint"},
+		{Category: "logpoint", Text: "hit"},
+	}
+	if got := s.Lines(); !reflect.DeepEqual(got, want) {
+		t.Errorf("got lines %+v, want %+v", got, want)
+	}
+}
@@ -3,9 +3,12 @@ package runner
 import (
 	"bufio"
 	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"github.com/google/go-dap"
 	"github.com/gookit/color"
+	"github.com/weinberg/concurrencyRunner/pkg/adapter"
 	"github.com/weinberg/concurrencyRunner/pkg/client"
 	"github.com/weinberg/concurrencyRunner/pkg/config"
 	"io"
@@ -19,27 +22,41 @@ import (
 
 type InstanceAdapter struct {
 	Client   *client.Client
-	Cmd      *exec.Cmd
-	Type     config.AdapterEnum
-	Url      string
+	Adapter  adapter.Adapter
+	Type     string
 	ThreadId int
 	// Breakpoints stores the responses from setBreakpoints so we can
 	// report on which breakpoint was hit
 	Breakpoints map[int]dap.Breakpoint
 	// Instance is the instance from the config file
 	Instance config.Instance
-}
-
-type DelveAdapterData struct {
-	Hostname string
-	NextPort int
+	// Execution receives the stopped, exited and terminated events
+	Execution *client.Subscription
+	// Output is the program output of the instance
+	Output *OutputStream
+	// ExitCode is set once the instance reports its exit code
+	ExitCode *int
+	// Terminated is set once the debug session of the instance has ended
+	Terminated bool
+	// launch is the pending launch or attach response, which some adapters
+	// only send after configurationDone
+	launch *client.Future
 }
 
 type Runtime struct {
+	ctx context.Context
 	// maps instance Id from config file to instance runtime data
 	InstanceAdapters map[string]*InstanceAdapter
-	// adapter specific data
-	DelveAdapterData *DelveAdapterData
+	// StepErrors are the errors from actions with continueOnError set
+	StepErrors []*StepError
+	// DefaultTimeout is the config's action timeout
+	DefaultTimeout time.Duration
+	// Step is the index of the action being run
+	Step int
+	// Trace is the evidence recorded while running the sequence
+	Trace []TraceEntry
+	// Assertions are the results of the assert actions
+	Assertions []AssertionResult
 }
 
 var instanceColors []color.Color = []color.Color{
@@ -50,36 +67,82 @@ var instanceColors []color.Color = []color.Color{
 
 const PREFIX_WIDTH int = 10
 
-func NewRuntime() *Runtime {
+// SETUP_TIMEOUT is how long to wait for an instance to stop on entry
+const SETUP_TIMEOUT = 60 * time.Second
+
+// DISCONNECT_TIMEOUT is how long to wait for an adapter to end a debug session
+const DISCONNECT_TIMEOUT = 5 * time.Second
+
+// DEFAULT_TIMEOUT is the action timeout in seconds when neither the action nor
+// the config specify one
+const DEFAULT_TIMEOUT time.Duration = 30
+
+func NewRuntime(ctx context.Context) *Runtime {
 	return &Runtime{
+		ctx:              ctx,
 		InstanceAdapters: make(map[string]*InstanceAdapter),
 	}
 }
 
-func NewDelveAdapterData() *DelveAdapterData {
-	return &DelveAdapterData{
-		Hostname: "localhost",
-		NextPort: 59000,
+func Run(ctx context.Context, c *config.Config) (verdict *Verdict, err error) {
+	for _, instance := range c.Instances {
+		err = adapter.Validate(instance.Adapter)
+		if err != nil {
+			return nil, fmt.Errorf("instance '%s': %w", instance.Id, err)
+		}
+	}
+
+	if c.Mode == config.ModeExplore {
+		return Explore(ctx, c)
+	}
+
+	result, err := RunScenario(ctx, c, "sequence")
+	if err != nil {
+		return nil, err
 	}
+
+	return &Verdict{Scenarios: []*ScenarioResult{result}}, nil
 }
 
-func Run(config *config.Config) (err error) {
-	r := NewRuntime()
-	err = r.LaunchClients(config)
+// RunScenario launches the instances, runs the config's sequence, checks the
+// invariants and cleans up. When a step fails the result has Err set and holds
+// the trace and assertions recorded up to that step, the invariants are not
+// checked.
+func RunScenario(ctx context.Context, c *config.Config, name string) (result *ScenarioResult, err error) {
+	err = RunSetup(c)
 	if err != nil {
-		return
+		return nil, err
 	}
-	defer r.Cleanup()
 
-	err = r.SetupInstances(config)
+	r := NewRuntime(ctx)
+	// clean up the instances which were launched even if others fail
+	defer r.Cleanup()
+	err = r.LaunchClients(c)
 	if err != nil {
-		return err
+		return
 	}
 
-	err = r.RunSequence(config)
+	err = r.SetupInstances(c)
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	result = &ScenarioResult{Name: name}
+	result.Err = r.RunSequence(c)
+	if ctx.Err() != nil {
+		// interrupted
+		return nil, ctx.Err()
 	}
+	if result.Err == nil {
+		result.Invariants = r.CheckInvariants(c)
+	}
+	// logpoints which fired after the last step, before the instances are
+	// disconnected
+	r.recordLogpointHits()
+
+	result.StepErrors = r.StepErrors
+	result.Trace = r.Trace
+	result.Assertions = r.Assertions
 
 	return
 }
@@ -88,20 +151,69 @@ func Run(config *config.Config) (err error) {
  * Cleanup
  ***************************************************/
 
+// Cleanup ends the debug session of each instance, then closes its client and
+// stops its adapter
 func (r *Runtime) Cleanup() (err error) {
 	for _, ia := range r.InstanceAdapters {
-		err = ia.Cmd.Process.Kill()
+		if ia.Client != nil {
+			disconnect(ia)
+			ia.Client.Close()
+		}
+		err = ia.Adapter.Shutdown()
 		if err != nil {
 			fmt.Printf("Error killing instance '%s': %s\n", ia.Instance.Name, err)
 		}
+		if ia.Output != nil {
+			err = ia.Output.Close()
+			if err != nil {
+				fmt.Printf("Error closing log file of instance '%s': %s\n", ia.Instance.Name, err)
+			}
+		}
 	}
 	return
 }
 
+// disconnect ends the debug session of ia. Launched programs are terminated,
+// which also lets the adapter remove any binary it built, while processes the
+// runner attached to are left running.
+func disconnect(ia *InstanceAdapter) {
+	select {
+	case <-ia.Client.Done():
+		return
+	default:
+	}
+
+	// the runtime's context may already be cancelled by a signal
+	ctx, cancel := context.WithTimeout(context.Background(), DISCONNECT_TIMEOUT)
+	defer cancel()
+	kill := ia.Instance.Request != config.RequestAttach
+	_, err := ia.Client.Disconnect(ctx, kill)
+	if err != nil {
+		fmt.Printf("Error disconnecting instance '%s': %s\n", ia.Instance.Name, err)
+	}
+}
+
 /****************************************************
  * Setup
  ***************************************************/
 
+// RunSetup runs the config's setup commands with `sh -c`
+func RunSetup(c *config.Config) error {
+	for _, hook := range c.Setup {
+		printPrefix(nil)
+		cl := color.C256(247)
+		cl.Printf("SETUP: %s\n", hook.Command)
+
+		cmd := exec.Command("sh", "-c", hook.Command)
+		cmd.Dir = hook.Cwd
+		output, err := cmd.CombinedOutput()
+		if err != nil {
+			return fmt.Errorf("setup command '%s' failed: %w: %s", hook.Command, err, bytes.TrimSpace(output))
+		}
+	}
+	return nil
+}
+
 func (r *Runtime) SetupInstances(c *config.Config) (err error) {
 	err = r.SetBreakpoints(c)
 	if err != nil {
@@ -116,52 +228,123 @@ func (r *Runtime) CompleteSetup(c *config.Config) (err error) {
 	for _, instance := range r.InstanceAdapters {
 		cl := instance.Client
 		// send configuration done
-		err = cl.ConfigurationDoneRequest()
+		_, err = cl.ConfigurationDone(r.ctx)
+		if err != nil {
+			return err
+		}
+
+		// launch or attach response comes after configuration done for
+		// some adapters
+		if instance.Instance.Request == config.RequestAttach {
+			_, err = client.WaitAttach(r.ctx, instance.launch)
+		} else {
+			_, err = client.WaitLaunch(r.ctx, instance.launch)
+		}
 		if err != nil {
 			return err
 		}
 
 		// get stopped event - expected after launch
-		<-cl.Events
+		event, err := r.waitStopped(instance, SETUP_TIMEOUT)
+		if err != nil {
+			return fmt.Errorf("instance '%s' did not stop on entry: %w", instance.Instance.Name, err)
+		}
+		instance.ThreadId = event.Body.ThreadId
 
-		// delve sends output event, ignore it
-		<-cl.Events
+		// from here on events are only read through subscriptions
+		cl.DropUnmatchedEvents()
+	}
 
-		// Configuration Done Response
-		<-cl.Responses
+	return
+}
 
-		// Get threads
-		err = cl.ThreadsRequest()
-		if err != nil {
-			return err
+// fileBreakpoints are the breakpoints of an instance in a single file
+type fileBreakpoints struct {
+	lines         []int
+	conditions    map[int]string
+	hitConditions map[int]string
+	logMessages   map[int]string
+}
+
+func newFileBreakpoints() *fileBreakpoints {
+	return &fileBreakpoints{
+		conditions:    make(map[int]string),
+		hitConditions: make(map[int]string),
+		logMessages:   make(map[int]string),
+	}
+}
+
+// add adds a breakpoint at line. A line may be paused at more than once but
+// always with the same conditions.
+func (f *fileBreakpoints) add(line int, condition, hitCondition string) error {
+	for _, l := range f.lines {
+		if l != line {
+			continue
+		}
+		if _, ok := f.logMessages[line]; ok {
+			return fmt.Errorf("line %d has both a pause and a logpoint", line)
 		}
+		if f.conditions[line] != condition || f.hitConditions[line] != hitCondition {
+			return fmt.Errorf("conflicting conditions for the breakpoint at line %d", line)
+		}
+		return nil
+	}
 
-		<-cl.Responses
-		/*
-			if len(threads.Body.Threads) > 1 {
-				return fmt.Errorf("client has more than 1 thread: %d", len(threads.Body.Threads))
-			}
-			if err != nil {
-				return err
-			}
+	f.lines = append(f.lines, line)
+	if condition != "" {
+		f.conditions[line] = condition
+	}
+	if hitCondition != "" {
+		f.hitConditions[line] = hitCondition
+	}
+	return nil
+}
 
-			instance.ThreadId = threads.Body.Threads[0].Id
-		*/
+// addLogpoint adds a logpoint at line. A logpoint does not stop the instance
+// so it cannot share its line with a pause.
+func (f *fileBreakpoints) addLogpoint(line int, message string) error {
+	for _, l := range f.lines {
+		if l != line {
+			continue
+		}
+		if existing, ok := f.logMessages[line]; !ok || existing != message {
+			return fmt.Errorf("line %d has more than one pause or logpoint", line)
+		}
+		return nil
 	}
 
-	return
+	f.lines = append(f.lines, line)
+	f.logMessages[line] = message
+	return nil
+}
+
+// fileBreakpointsFor returns the breakpoints of an instance in file, adding
+// them to breakpoints if needed
+func fileBreakpointsFor(breakpoints map[string]map[string]*fileBreakpoints, instanceId, file string) *fileBreakpoints {
+	if _, ok := breakpoints[instanceId]; !ok {
+		breakpoints[instanceId] = make(map[string]*fileBreakpoints)
+	}
+	if _, ok := breakpoints[instanceId][file]; !ok {
+		breakpoints[instanceId][file] = newFileBreakpoints()
+	}
+	return breakpoints[instanceId][file]
 }
 
 // SetBreakpoints sets breakpoints in all instances
 func (r *Runtime) SetBreakpoints(c *config.Config) (err error) {
-	// map of instanceId -> map of file -> lines
-	breakpoints := make(map[string]map[string][]int)
+	// map of instanceId -> map of file -> breakpoints
+	breakpoints := make(map[string]map[string]*fileBreakpoints)
 
-	for _, action := range c.Sequence {
+	for i, action := range c.Sequence {
 		if action.Type != config.ActionTypePause {
 			continue
 		}
 
+		_, err := r.instanceAdapter(action.InstanceId)
+		if err != nil {
+			return &StepError{Step: i, InstanceId: action.InstanceId, Action: action.Type, Err: err}
+		}
+
 		line, err := findTargetComment(action.File, action.TargetComment)
 		if err != nil {
 			return err
@@ -172,31 +355,49 @@ func (r *Runtime) SetBreakpoints(c *config.Config) (err error) {
 			return err
 		}
 
-		if _, ok := breakpoints[action.InstanceId]; !ok {
-			breakpoints[action.InstanceId] = make(map[string][]int)
+		err = fileBreakpointsFor(breakpoints, action.InstanceId, absFilepath).add(line, action.Condition, action.HitCondition)
+		if err != nil {
+			return fmt.Errorf("file '%s': %w", action.File, err)
 		}
+	}
+
+	for _, instance := range c.Instances {
+		var locations []string
+		for _, logpoint := range instance.Logpoints {
+			line, err := findTargetComment(logpoint.File, logpoint.TargetComment)
+			if err != nil {
+				return err
+			}
+
+			absFilepath, err := filepath.Abs(logpoint.File)
+			if err != nil {
+				return err
+			}
 
-		if _, ok := breakpoints[action.InstanceId][absFilepath]; !ok {
-			breakpoints[action.InstanceId][absFilepath] = []int{}
+			err = fileBreakpointsFor(breakpoints, instance.Id, absFilepath).addLogpoint(line, logpoint.Message)
+			if err != nil {
+				return fmt.Errorf("file '%s': %w", logpoint.File, err)
+			}
+			locations = append(locations, logpointLocation(absFilepath, line))
+		}
+		if ia, ok := r.InstanceAdapters[instance.Id]; ok && len(locations) > 0 {
+			ia.Output.setLogpoints(locations)
 		}
-		breakpoints[action.InstanceId][absFilepath] = append(breakpoints[action.InstanceId][absFilepath], line)
 	}
 
 	for instanceId, bpData := range breakpoints {
 		c := r.InstanceAdapters[instanceId].Client
-		for file, lines := range bpData {
-			err := c.SetBreakpointsRequestWithArgs(file, lines, nil, nil, nil)
-			if err != nil {
-				return err
-			}
-			breakpointsResponse, err := c.ReadSetBreakpointsResponse()
+		lazy, _ := r.InstanceAdapters[instanceId].Adapter.(adapter.LazyBreakpoints)
+		for file, fileBps := range bpData {
+			lines := fileBps.lines
+			breakpointsResponse, err := c.SetBreakpoints(r.ctx, file, lines, fileBps.conditions, fileBps.hitConditions, fileBps.logMessages)
 			if err != nil {
 				return err
 			}
 			breakpoints := breakpointsResponse.Body.Breakpoints
 			//var i int
 			for _, response := range breakpointsResponse.Body.Breakpoints {
-				if response.Verified == false {
+				if response.Verified == false && (lazy == nil || !lazy.LazyBreakpoints()) {
 					return fmt.Errorf("breakpoint could not be set in file '%s' at line '%d': %s",
 						file, response.Line, breakpointsResponse.Body.Breakpoints[0].Message)
 				}
@@ -247,26 +448,100 @@ func findTargetComment(file string, comment string) (int, error) {
  * Run Sequence
  ***************************************************/
 
+// StepError is returned when an action in the sequence fails
+type StepError struct {
+	Step       int
+	InstanceId string
+	Action     config.ActionTypeEnum
+	Err        error
+}
+
+func (e *StepError) Error() string {
+	if e.InstanceId == "" {
+		return fmt.Sprintf("step %d (%s): %s", e.Step, e.Action, e.Err)
+	}
+	return fmt.Sprintf("step %d (%s, instance '%s'): %s", e.Step, e.Action, e.InstanceId, e.Err)
+}
+
+func (e *StepError) Unwrap() error {
+	return e.Err
+}
+
 func (r *Runtime) RunSequence(c *config.Config) (err error) {
-	for _, action := range c.Sequence {
+	r.DefaultTimeout = c.Timeout
+	for i, action := range c.Sequence {
+		// stop when interrupted
+		if err = r.ctx.Err(); err != nil {
+			return err
+		}
+		r.Step = i
 		switch action.Type {
 		case config.ActionTypeRun:
-			r.actionRun(action)
+			err = r.actionRun(action)
 		case config.ActionTypePause:
-			r.actionPause(action)
+			err = r.actionPause(action)
 		case config.ActionTypeContinue:
-			r.actionContinue(action)
+			err = r.actionContinue(action)
 		case config.ActionTypeSleep:
-			r.actionSleep(action)
+			err = r.actionSleep(action)
+		case config.ActionTypeExpectBlocked:
+			err = r.actionExpectBlocked(action)
+		case config.ActionTypeExpectUnblocked:
+			err = r.actionExpectUnblocked(action)
+		case config.ActionTypeWaitForOutput:
+			err = r.actionWaitForOutput(action)
+		case config.ActionTypeWaitExit:
+			err = r.actionWaitExit(action)
+		case config.ActionTypeNext, config.ActionTypeStepIn, config.ActionTypeStepOut:
+			err = r.actionStep(action)
+		case config.ActionTypeSet:
+			err = r.actionSet(action)
+		case config.ActionTypeAssert:
+			err = r.actionAssert(action)
+		default:
+			err = fmt.Errorf("unknown action type")
+		}
+		r.recordLogpointHits()
+
+		if err != nil {
+			stepErr := &StepError{
+				Step:       i,
+				InstanceId: action.InstanceId,
+				Action:     action.Type,
+				Err:        err,
+			}
+			if !action.ContinueOnError {
+				return stepErr
+			}
+			printPrefix(nil)
+			cl := color.C256(247)
+			cl.Printf("ERROR (continuing): %s\n", stepErr)
+			r.StepErrors = append(r.StepErrors, stepErr)
+			err = nil
 		}
 	}
 	return
 }
 
-func (r *Runtime) drainEvents() {
-	for _, ia := range r.InstanceAdapters {
-		ia.Client.ReadMessage()
+// instanceAdapter returns the runtime data for the instance with id
+func (r *Runtime) instanceAdapter(id string) (*InstanceAdapter, error) {
+	ia, ok := r.InstanceAdapters[id]
+	if !ok {
+		return nil, fmt.Errorf("unknown instance '%s'", id)
 	}
+	return ia, nil
+}
+
+// actionTimeout returns how long an action may wait on an instance
+func (r *Runtime) actionTimeout(action config.Action) time.Duration {
+	timeout := action.Timeout
+	if timeout == 0 {
+		timeout = r.DefaultTimeout
+	}
+	if timeout == 0 {
+		timeout = DEFAULT_TIMEOUT
+	}
+	return timeout * time.Second
 }
 
 func (r *Runtime) actionSleep(action config.Action) (err error) {
@@ -278,16 +553,23 @@ func (r *Runtime) actionSleep(action config.Action) (err error) {
 	c := color.C256(247)
 	c.Printf("ACTION: SLEEP %d second%s\n", action.SleepDuration, suffix)
 
-	time.Sleep(action.SleepDuration * time.Second)
+	select {
+	case <-time.After(action.SleepDuration * time.Second):
+	case <-r.ctx.Done():
+		return r.ctx.Err()
+	}
 
 	return
 }
 
 func (r *Runtime) actionContinue(action config.Action) (err error) {
-	ia := r.InstanceAdapters[action.InstanceId]
+	ia, err := r.instanceAdapter(action.InstanceId)
+	if err != nil {
+		return err
+	}
 	cl := ia.Client
 
-	err = cl.ContinueRequest(ia.ThreadId)
+	_, err = cl.Continue(r.ctx, ia.ThreadId)
 	if err != nil {
 		return err
 	}
@@ -299,15 +581,53 @@ func (r *Runtime) actionContinue(action config.Action) (err error) {
 	return
 }
 
+// waitStopped waits for the stopped event of ia. Exiting or terminating
+// instead is an error.
+func (r *Runtime) waitStopped(ia *InstanceAdapter, timeout time.Duration) (*dap.StoppedEvent, error) {
+	m, err := ia.Execution.NextWithTimeout(r.ctx, timeout)
+	if err != nil {
+		return nil, err
+	}
+	switch m := m.(type) {
+	case *dap.StoppedEvent:
+		return m, nil
+	case *dap.ExitedEvent:
+		return nil, fmt.Errorf("exited with code %d", m.Body.ExitCode)
+	default:
+		return nil, errors.New("terminated")
+	}
+}
+
 func (r *Runtime) actionPause(action config.Action) (err error) {
-	ia := r.InstanceAdapters[action.InstanceId]
-	cl := ia.Client
+	ia, err := r.instanceAdapter(action.InstanceId)
+	if err != nil {
+		return err
+	}
 
-	event, err := cl.ReadStoppedEvent()
+	timeout := r.actionTimeout(action)
+	m, err := ia.Execution.NextWithTimeout(r.ctx, timeout)
+	if errors.Is(err, client.ErrTimeout) {
+		return r.diagnoseTimeout(ia, fmt.Sprintf("reach '%s'", action.TargetComment), timeout)
+	}
 	if err != nil {
 		return err
 	}
 
+	noteExit(ia, m)
+
+	var event *dap.StoppedEvent
+	switch m := m.(type) {
+	case *dap.StoppedEvent:
+		event = m
+	case *dap.ExitedEvent:
+		return fmt.Errorf("exited with code %d before reaching '%s'", m.Body.ExitCode, action.TargetComment)
+	case *dap.TerminatedEvent:
+		return fmt.Errorf("terminated before reaching '%s'", action.TargetComment)
+	default:
+		return fmt.Errorf("expected stopped event, got %T", m)
+	}
+	ia.ThreadId = event.Body.ThreadId
+
 	for _, id := range event.Body.HitBreakpointIds {
 		printPrefix(&ia.Instance)
 		c := color.C256(247)
@@ -315,18 +635,136 @@ func (r *Runtime) actionPause(action config.Action) (err error) {
 			ia.Breakpoints[id].Source.Path, ia.Breakpoints[id].Line)
 	}
 
+	// not all adapters report which breakpoint was hit
+	if len(event.Body.HitBreakpointIds) == 0 {
+		printPrefix(&ia.Instance)
+		c := color.C256(247)
+		c.Printf("ACTION: PAUSE at '%s' (%s)\n", action.TargetComment, event.Body.Reason)
+	}
+
+	return r.captureValues(ia, action)
+}
+
+// actionWaitExit waits for an instance to exit and checks its exit code
+// against the action's expectExitCode, if set
+func (r *Runtime) actionWaitExit(action config.Action) (err error) {
+	ia, err := r.instanceAdapter(action.InstanceId)
+	if err != nil {
+		return err
+	}
+
+	timeout := r.actionTimeout(action)
+	printPrefix(&ia.Instance)
+	c := color.C256(247)
+	c.Printf("ACTION: WAIT EXIT\n")
+
+	err = r.waitExit(ia, timeout)
+	if errors.Is(err, client.ErrTimeout) {
+		return r.diagnoseTimeout(ia, "exit", timeout)
+	}
+	if err != nil {
+		return err
+	}
+
+	message := "terminated"
+	if ia.ExitCode != nil {
+		message = fmt.Sprintf("exited with code %d", *ia.ExitCode)
+	}
+	printPrefix(&ia.Instance)
+	c.Printf("EXIT: %s\n", message)
+	r.record(action, TraceEntry{Message: message})
+
+	if action.ExpectExitCode == nil {
+		return
+	}
+	if ia.ExitCode == nil {
+		return fmt.Errorf("expected exit code %d but the instance terminated without reporting one", *action.ExpectExitCode)
+	}
+	if *ia.ExitCode != *action.ExpectExitCode {
+		return fmt.Errorf("expected exit code %d, got %d", *action.ExpectExitCode, *ia.ExitCode)
+	}
 	return
 }
 
+// waitExit reads the events of ia until its debug session terminates
+func (r *Runtime) waitExit(ia *InstanceAdapter, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for !ia.Terminated {
+		m, err := ia.Execution.NextWithTimeout(r.ctx, time.Until(deadline))
+		if errors.Is(err, client.ErrClosed) {
+			// the adapter ends the connection once the session is over
+			ia.Terminated = true
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if event, ok := m.(*dap.StoppedEvent); ok {
+			ia.ThreadId = event.Body.ThreadId
+			return fmt.Errorf("expected instance to exit but it %s", describeUnblockEvent(ia, m))
+		}
+		noteExit(ia, m)
+	}
+	return nil
+}
+
+// noteExit records the exit code or termination of ia from an event
+func noteExit(ia *InstanceAdapter, m dap.Message) {
+	switch event := m.(type) {
+	case *dap.ExitedEvent:
+		exitCode := event.Body.ExitCode
+		ia.ExitCode = &exitCode
+	case *dap.TerminatedEvent:
+		ia.Terminated = true
+	}
+}
+
+// TimeoutError is returned when an instance does not do what a step expects
+// of it within the timeout
+type TimeoutError struct {
+	Expected string
+	Timeout  time.Duration
+	// Stacks show where the instance was halted
+	Stacks []ThreadStack
+	// Err is set when the instance could not be halted
+	Err error
+}
+
+func (e *TimeoutError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("did not %s within %s and could not capture stacks: %s", e.Expected, e.Timeout, e.Err)
+	}
+	return fmt.Sprintf("did not %s within %s, halted in %s", e.Expected, e.Timeout, stacksLocation(e.Stacks))
+}
+
+func (e *TimeoutError) Unwrap() error {
+	return e.Err
+}
+
+// diagnoseTimeout halts an instance which did not do what it was expected to
+// within timeout, prints where it actually is and returns a *TimeoutError
+// describing it
+func (r *Runtime) diagnoseTimeout(ia *InstanceAdapter, expected string, timeout time.Duration) error {
+	printPrefix(&ia.Instance)
+	c := color.C256(247)
+	c.Printf("TIMEOUT waiting to %s after %s, halting instance\n", expected, timeout)
+
+	stacks, err := r.haltAndCaptureStacks(ia)
+	if err != nil {
+		return &TimeoutError{Expected: expected, Timeout: timeout, Err: err}
+	}
+	printStacks(ia, stacks)
+
+	return &TimeoutError{Expected: expected, Timeout: timeout, Stacks: stacks}
+}
+
 func (r *Runtime) actionRun(action config.Action) (err error) {
-	ia := r.InstanceAdapters[action.InstanceId]
-	cl := ia.Client
-	err = cl.ContinueRequest(ia.ThreadId)
+	ia, err := r.instanceAdapter(action.InstanceId)
 	if err != nil {
 		return err
 	}
-
-	_, err = cl.ReadContinueResponse()
+	cl := ia.Client
+	_, err = cl.Continue(r.ctx, ia.ThreadId)
 	if err != nil {
 		return err
 	}
@@ -374,135 +812,150 @@ func (r *Runtime) LaunchClients(config *config.Config) (err error) {
 
 func (r *Runtime) LaunchClient(instance config.Instance) (*client.Client, error) {
 	// each client requires its own DAP
-	err := r.LaunchDAP(instance)
+	cl, err := r.LaunchDAP(instance)
 	if err != nil {
 		return nil, err
 	}
+	ad := r.InstanceAdapters[instance.Id].Adapter
 
-	// create client
-	clientUrl := r.InstanceAdapters[instance.Id].Url
-	cl, err := client.NewClient(clientUrl)
+	// initialize
+	_, err = cl.Initialize(r.ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	// initialize
-	_, err = cl.Initialize()
+	ia := r.InstanceAdapters[instance.Id]
+	if instance.Request == config.RequestAttach {
+		ia.launch, err = attachDebugee(r.ctx, cl, ad, instance)
+	} else {
+		ia.launch, err = launchDebugee(r.ctx, cl, ad, instance)
+	}
 	if err != nil {
 		return nil, err
 	}
 
-	// extract env vars
-	envVars := make(map[string]string)
-	envKvs := strings.Split(instance.Env, ";")
-	for _, envKv := range envKvs {
-		kv := strings.Split(envKv, "=")
-		if kv[0] == "" || kv[1] == "" {
-			return nil, fmt.Errorf("invalid env string: %s", instance.Env)
-		}
-		envVars[kv[0]] = kv[1]
-	}
-
-	// launch debugee
-	err = cl.LaunchRequestWithArgs(map[string]interface{}{
-		"request":     "launch",
-		"mode":        "debug",
-		"program":     instance.Program,
-		"stopOnEntry": true,
-		"env":         envVars,
-		"dlvCwd":      instance.Cwd,
-		"args":        instance.Args,
-	})
-	_, err = cl.ReadInitializedEvent()
+	return cl, nil
+}
+
+// launchDebugee sends the launch request and waits for the initialized event.
+// The returned future resolves to the launch response.
+func launchDebugee(ctx context.Context, cl *client.Client, ad adapter.Adapter, instance config.Instance) (*client.Future, error) {
+	launchArgs, err := ad.LaunchArgs(instance)
 	if err != nil {
 		return nil, err
 	}
-
-	// launch response comes after initialized event
-	_, err = cl.ReadLaunchResponse()
+	initialized := cl.Subscribe(client.EventTypes("initialized"))
+	defer initialized.Unsubscribe()
+	f, err := cl.LaunchRequestWithArgs(launchArgs)
 	if err != nil {
 		return nil, err
 	}
-
-	return cl, nil
+	err = waitInitialized(ctx, initialized, f)
+	if err != nil {
+		return nil, err
+	}
+	return f, nil
 }
 
-func (r *Runtime) LaunchDAP(instance config.Instance) (err error) {
-	var instanceAdapter *InstanceAdapter
-	switch instance.Adapter {
-	case config.AdapterDelve:
-		{
-			instanceAdapter, err = r.LaunchDelveAdapter(instance)
-			if err != nil {
-				return err
-			}
-		}
+// attachDebugee sends the attach request and waits for the initialized event.
+// The returned future resolves to the attach response.
+func attachDebugee(ctx context.Context, cl *client.Client, ad adapter.Adapter, instance config.Instance) (*client.Future, error) {
+	attacher, ok := ad.(adapter.Attacher)
+	if !ok {
+		return nil, fmt.Errorf("adapter '%s' does not support attach", instance.Adapter)
 	}
-
-	instanceAdapter.Instance = instance
-	r.InstanceAdapters[instance.Id] = instanceAdapter
-
-	return nil
+	attachArgs, err := attacher.AttachArgs(instance)
+	if err != nil {
+		return nil, err
+	}
+	initialized := cl.Subscribe(client.EventTypes("initialized"))
+	defer initialized.Unsubscribe()
+	f, err := cl.AttachRequest(attachArgs)
+	if err != nil {
+		return nil, err
+	}
+	err = waitInitialized(ctx, initialized, f)
+	if err != nil {
+		return nil, err
+	}
+	return f, nil
 }
 
-func childOutputToStdout(instance config.Instance, stdout io.ReadCloser) {
-	for {
-		tmp := make([]byte, 1024)
-		_, err := stdout.Read(tmp)
-		// Remove any NULL characters from 'b'
-		tmp = bytes.Trim(tmp, "\x00")
-		if bytes.HasSuffix(tmp, []byte("\n")) {
-			tmp = bytes.Trim(tmp, "\n")
-		}
-		tmpString := string(tmp)
-
-		if strings.HasPrefix(tmpString, "DAP server") {
-			continue
-		}
-
-		prefix := getPrefix(&instance)
-		label := "STDOUT: "
-		c := color.C256(247)
-		label = c.Sprintf("%s", label)
-		prefix = prefix + label
-
-		tmpString = strings.Replace(tmpString, "\n", "\n"+prefix, -1)
-
-		fmt.Printf("%s%s\n", prefix, tmpString)
+// waitInitialized waits for the initialized event which follows the launch or
+// attach request f on the subscription sub. An adapter which cannot start the
+// debuggee sends an error response instead, so the response is watched as
+// well and returned if it is an error.
+func waitInitialized(ctx context.Context, sub *client.Subscription, f *client.Future) error {
+	ctx, cancel := context.WithTimeout(ctx, SETUP_TIMEOUT)
+	defer cancel()
+
+	initialized := make(chan error, 1)
+	go func() {
+		_, err := sub.Next(ctx)
+		initialized <- err
+	}()
+
+	var err error
+	select {
+	case err = <-initialized:
+	case <-f.Done():
+		_, err = f.Wait(ctx)
 		if err != nil {
-			break
+			return err
 		}
+		// some adapters respond before sending the initialized event
+		err = <-initialized
 	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return fmt.Errorf("no initialized event after %s within %s", f.Command, SETUP_TIMEOUT)
+	}
+	return err
 }
 
-func (r *Runtime) LaunchDelveAdapter(instance config.Instance) (instanceAdapter *InstanceAdapter, err error) {
-	instanceAdapter = &InstanceAdapter{
-		Type:        config.AdapterDelve,
-		Breakpoints: map[int]dap.Breakpoint{},
+// LaunchDAP starts the debug adapter for instance and returns a client
+// connected to it
+func (r *Runtime) LaunchDAP(instance config.Instance) (*client.Client, error) {
+	ad, err := adapter.New(instance.Adapter)
+	if err != nil {
+		return nil, err
 	}
-	if r.DelveAdapterData == nil {
-		r.DelveAdapterData = NewDelveAdapterData()
+
+	instanceAdapter := &InstanceAdapter{
+		Adapter:     ad,
+		Type:        instance.Adapter,
+		Breakpoints: map[int]dap.Breakpoint{},
+		Instance:    instance,
 	}
-	instanceAdapter.Url = fmt.Sprintf("%s:%d", r.DelveAdapterData.Hostname, r.DelveAdapterData.NextPort)
-	r.DelveAdapterData.NextPort++
+	r.InstanceAdapters[instance.Id] = instanceAdapter
 
-	cmd := exec.Command("dlv", "dap", "--listen", instanceAdapter.Url)
-	stdout, err := cmd.StdoutPipe()
-	cmd.Stderr = cmd.Stdout
+	cl, err := ad.Launch(r.ctx, instance)
+	if output := ad.Output(); output != nil {
+		go adapterOutputToStdout(instanceAdapter, output)
+	}
 	if err != nil {
-		log.Fatalf("LaunchDelveAdapter: cannot start delve: %s", err.Error())
+		return nil, err
 	}
-	err = cmd.Start()
+	instanceAdapter.Client = cl
+	instanceAdapter.Execution = cl.Subscribe(client.EventTypes("stopped", "exited", "terminated"))
+	instanceAdapter.Output, err = newOutputStream(r.ctx, instanceAdapter)
 	if err != nil {
-		log.Fatalf("LaunchDelveAdapter: cannot start delve: %s", err.Error())
+		return nil, err
 	}
 
-	go childOutputToStdout(instance, stdout)
-
-	instanceAdapter.Cmd = cmd
+	return cl, nil
+}
 
-	// delay briefly to let adapter open port
-	time.Sleep(100 * time.Millisecond)
+// adapterOutputToStdout prints the output of the adapter process. Program
+// output arrives through DAP output events instead, see OutputStream.
+func adapterOutputToStdout(ia *InstanceAdapter, output io.Reader) {
+	scanner := bufio.NewScanner(output)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if ia.Adapter.IsChatter(line) {
+			continue
+		}
 
-	return
+		c := color.C256(247)
+		fmt.Printf("%s%s%s\n", getPrefix(&ia.Instance), c.Sprintf("ADAPTER: "), line)
+	}
 }
@@ -0,0 +1,165 @@
+package runner
+
+import (
+	"reflect"
+	"testing"
+)
+
+// breakpoint is a call to fileBreakpoints.add
+type breakpoint struct {
+	line         int
+	condition    string
+	hitCondition string
+}
+
+func TestFileBreakpointsAdd(t *testing.T) {
+	tests := []struct {
+		name              string
+		breakpoints       []breakpoint
+		wantErr           bool
+		wantLines         []int
+		wantConditions    map[int]string
+		wantHitConditions map[int]string
+	}{
+		{
+			name:              "different lines",
+			breakpoints:       []breakpoint{{line: 10}, {line: 20, condition: "i > 1"}},
+			wantLines:         []int{10, 20},
+			wantConditions:    map[int]string{20: "i > 1"},
+			wantHitConditions: map[int]string{},
+		},
+		{
+			name:              "same line without conditions",
+			breakpoints:       []breakpoint{{line: 10}, {line: 10}},
+			wantLines:         []int{10},
+			wantConditions:    map[int]string{},
+			wantHitConditions: map[int]string{},
+		},
+		{
+			name: "same line with the same conditions",
+			breakpoints: []breakpoint{
+				{line: 10, condition: "i > 1", hitCondition: "2"},
+				{line: 10, condition: "i > 1", hitCondition: "2"},
+			},
+			wantLines:         []int{10},
+			wantConditions:    map[int]string{10: "i > 1"},
+			wantHitConditions: map[int]string{10: "2"},
+		},
+		{
+			name:        "same line with a different condition",
+			breakpoints: []breakpoint{{line: 10, condition: "i > 1"}, {line: 10, condition: "i > 2"}},
+			wantErr:     true,
+		},
+		{
+			name:        "same line with and without a condition",
+			breakpoints: []breakpoint{{line: 10}, {line: 10, condition: "i > 1"}},
+			wantErr:     true,
+		},
+		{
+			name:        "same line with a different hit condition",
+			breakpoints: []breakpoint{{line: 10, hitCondition: "2"}, {line: 10, hitCondition: "3"}},
+			wantErr:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFileBreakpoints()
+			var err error
+			for _, b := range tt.breakpoints {
+				err = f.add(b.line, b.condition, b.hitCondition)
+				if err != nil {
+					break
+				}
+			}
+
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(f.lines, tt.wantLines) {
+				t.Errorf("got lines %v, want %v", f.lines, tt.wantLines)
+			}
+			if !reflect.DeepEqual(f.conditions, tt.wantConditions) {
+				t.Errorf("got conditions %v, want %v", f.conditions, tt.wantConditions)
+			}
+			if !reflect.DeepEqual(f.hitConditions, tt.wantHitConditions) {
+				t.Errorf("got hit conditions %v, want %v", f.hitConditions, tt.wantHitConditions)
+			}
+		})
+	}
+}
+
+func TestFileBreakpointsAddLogpoint(t *testing.T) {
+	t.Run("pause then logpoint on the same line", func(t *testing.T) {
+		f := newFileBreakpoints()
+		err := f.add(10, "", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := f.addLogpoint(10, "i={i}"); err == nil {
+			t.Error("expected an error")
+		}
+	})
+
+	t.Run("logpoint then pause on the same line", func(t *testing.T) {
+		f := newFileBreakpoints()
+		err := f.addLogpoint(10, "i={i}")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := f.add(10, "", ""); err == nil {
+			t.Error("expected an error")
+		}
+	})
+
+	t.Run("same logpoint twice", func(t *testing.T) {
+		f := newFileBreakpoints()
+		for i := 0; i < 2; i++ {
+			err := f.addLogpoint(10, "i={i}")
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		if !reflect.DeepEqual(f.lines, []int{10}) {
+			t.Errorf("got lines %v, want [10]", f.lines)
+		}
+		if f.logMessages[10] != "i={i}" {
+			t.Errorf("got message %q, want %q", f.logMessages[10], "i={i}")
+		}
+	})
+
+	t.Run("different logpoints on the same line", func(t *testing.T) {
+		f := newFileBreakpoints()
+		err := f.addLogpoint(10, "i={i}")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := f.addLogpoint(10, "j={j}"); err == nil {
+			t.Error("expected an error")
+		}
+	})
+
+	t.Run("logpoint and pause on different lines", func(t *testing.T) {
+		f := newFileBreakpoints()
+		err := f.addLogpoint(10, "i={i}")
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = f.add(20, "i > 1", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(f.lines, []int{10, 20}) {
+			t.Errorf("got lines %v, want [10 20]", f.lines)
+		}
+		if _, ok := f.logMessages[20]; ok {
+			t.Error("pause has a log message")
+		}
+	})
+}
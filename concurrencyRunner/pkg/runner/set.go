@@ -0,0 +1,78 @@
+package runner
+
+import (
+	"fmt"
+	"github.com/gookit/color"
+	"github.com/weinberg/concurrencyRunner/pkg/config"
+)
+
+/****************************************************
+ * Variable assignment
+ ***************************************************/
+
+// actionSet changes the state of a stopped instance, either by overwriting a
+// variable of the top frame with setVariable or by evaluating an expression
+// such as an assignment
+func (r *Runtime) actionSet(action config.Action) (err error) {
+	ia, err := r.instanceAdapter(action.InstanceId)
+	if err != nil {
+		return err
+	}
+	if action.Variable == "" && action.Expression == "" {
+		return fmt.Errorf("set requires a variable or an expression")
+	}
+
+	frameId, err := r.topFrameId(ia)
+	if err != nil {
+		return err
+	}
+
+	var message string
+	if action.Variable != "" {
+		message, err = r.setVariable(ia, frameId, action.Variable, action.Value)
+	} else {
+		message, err = r.evaluateAssignment(ia, frameId, action.Expression)
+	}
+	if err != nil {
+		return err
+	}
+
+	printPrefix(&ia.Instance)
+	c := color.C256(247)
+	c.Printf("ACTION: SET %s\n", message)
+	r.record(action, TraceEntry{Message: fmt.Sprintf("set %s", message)})
+
+	return
+}
+
+// setVariable overwrites the variable name in the frame's scopes
+func (r *Runtime) setVariable(ia *InstanceAdapter, frameId int, name, value string) (string, error) {
+	variables, err := r.frameVariables(ia, frameId)
+	if err != nil {
+		return "", err
+	}
+	variable, ok := variables[name]
+	if !ok {
+		return "", fmt.Errorf("variable '%s' not found in the top frame", name)
+	}
+
+	setVariableResponse, err := ia.Client.SetVariable(r.ctx, variable.Scope, name, value)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s=%s (was %s)", name, setVariableResponse.Body.Value, variable.Value), nil
+}
+
+// evaluateAssignment evaluates expression for its side effects. The repl
+// context is used since that is where adapters such as debugpy accept
+// statements.
+func (r *Runtime) evaluateAssignment(ia *InstanceAdapter, frameId int, expression string) (string, error) {
+	evaluateResponse, err := ia.Client.Evaluate(r.ctx, expression, frameId, "repl")
+	if err != nil {
+		return "", err
+	}
+	if evaluateResponse.Body.Result == "" {
+		return expression, nil
+	}
+	return fmt.Sprintf("%s -> %s", expression, evaluateResponse.Body.Result), nil
+}
@@ -0,0 +1,69 @@
+package runner
+
+import (
+	"errors"
+	"fmt"
+	"github.com/gookit/color"
+	"github.com/weinberg/concurrencyRunner/pkg/client"
+	"github.com/weinberg/concurrencyRunner/pkg/config"
+	"strings"
+)
+
+/****************************************************
+ * Stepping
+ ***************************************************/
+
+// actionStep steps a paused instance with next, stepIn or stepOut as many
+// times as the action's count. The instance is paused at the new location
+// afterwards, as after a pause action.
+func (r *Runtime) actionStep(action config.Action) (err error) {
+	ia, err := r.instanceAdapter(action.InstanceId)
+	if err != nil {
+		return err
+	}
+	cl := ia.Client
+
+	count := action.Count
+	if count == 0 {
+		count = 1
+	}
+	name := strings.ToUpper(action.Type.String())
+	printPrefix(&ia.Instance)
+	c := color.C256(247)
+	c.Printf("ACTION: %s x%d\n", name, count)
+
+	timeout := r.actionTimeout(action)
+	for i := 0; i < count; i++ {
+		switch action.Type {
+		case config.ActionTypeNext:
+			_, err = cl.Next(r.ctx, ia.ThreadId)
+		case config.ActionTypeStepIn:
+			_, err = cl.StepIn(r.ctx, ia.ThreadId)
+		case config.ActionTypeStepOut:
+			_, err = cl.StepOut(r.ctx, ia.ThreadId)
+		}
+		if err != nil {
+			return err
+		}
+
+		event, err := r.waitStopped(ia, timeout)
+		if errors.Is(err, client.ErrTimeout) {
+			return r.diagnoseTimeout(ia, fmt.Sprintf("complete step %d", i+1), timeout)
+		}
+		if err != nil {
+			return fmt.Errorf("step %d: %w", i+1, err)
+		}
+		ia.ThreadId = event.Body.ThreadId
+	}
+
+	stackTrace, err := cl.StackTrace(r.ctx, ia.ThreadId, 0, 1)
+	if err != nil {
+		return err
+	}
+	location := ThreadStack{Frames: stackTrace.Body.StackFrames}.location()
+	printPrefix(&ia.Instance)
+	c.Printf("%s to %s\n", name, location)
+	r.record(action, TraceEntry{Message: fmt.Sprintf("stepped to %s", location)})
+
+	return r.captureValues(ia, action)
+}
@@ -0,0 +1,95 @@
+package runner
+
+import (
+	"fmt"
+	"github.com/gookit/color"
+	"github.com/weinberg/concurrencyRunner/pkg/config"
+	"sort"
+	"time"
+)
+
+// NO_STEP is the step of trace entries which were not recorded by a step of
+// the sequence, such as logpoint hits
+const NO_STEP = -1
+
+// TraceEntry records evidence observed while running a step of the sequence.
+// The trace is ordered by Time.
+type TraceEntry struct {
+	Step       int
+	InstanceId string
+	Action     config.ActionTypeEnum
+	Message    string
+	Stacks     []ThreadStack
+	Captures   []Capture
+	// Time is when the evidence was observed
+	Time time.Time
+}
+
+// record adds an entry for the current step to the trace
+func (r *Runtime) record(action config.Action, entry TraceEntry) {
+	entry.Step = r.Step
+	entry.InstanceId = action.InstanceId
+	entry.Action = action.Type
+	entry.Time = time.Now()
+	r.Trace = append(r.Trace, entry)
+}
+
+// recordLogpointHits adds the messages printed by logpoints since the last
+// call to the trace. Logpoints fire while instances run, during setup and
+// between steps as well as during them, so hits are placed in the trace by
+// the time they arrived rather than attributed to a step.
+func (r *Runtime) recordLogpointHits() {
+	added := false
+	for _, ia := range r.InstanceAdapters {
+		if ia.Output == nil {
+			continue
+		}
+		for _, hit := range ia.Output.takeLogpointHits() {
+			r.Trace = append(r.Trace, TraceEntry{
+				Step:       NO_STEP,
+				InstanceId: ia.Instance.Id,
+				Message:    fmt.Sprintf("logpoint at %s: %s", hit.Location, hit.Message),
+				Time:       hit.Time,
+			})
+			added = true
+		}
+	}
+	if added {
+		sort.SliceStable(r.Trace, func(i, j int) bool {
+			return r.Trace[i].Time.Before(r.Trace[j].Time)
+		})
+	}
+}
+
+// printTrace prints the trace of a scenario: the captured values, blocked
+// stacks, logpoint hits and other evidence in the order it was observed
+func printTrace(trace []TraceEntry) {
+	if len(trace) == 0 {
+		return
+	}
+	c := color.C256(247)
+	printPrefix(nil)
+	c.Printf("  trace:\n")
+	for _, entry := range trace {
+		printPrefix(nil)
+		c.Printf("    +%.3fs %s\n", entry.Time.Sub(trace[0].Time).Seconds(), entry)
+		for _, stack := range entry.Stacks {
+			printPrefix(nil)
+			c.Printf("        THREAD %s\n", stack.Thread.Name)
+			for _, frame := range stack.Frames {
+				printPrefix(nil)
+				c.Printf("            %s (%s:%d)\n", frame.Name, frame.Source.Path, frame.Line)
+			}
+		}
+	}
+}
+
+func (e TraceEntry) String() string {
+	if e.Step == NO_STEP {
+		return fmt.Sprintf("instance '%s': %s", e.InstanceId, e.Message)
+	}
+	if e.InstanceId == "" {
+		return fmt.Sprintf("step %d (%s): %s", e.Step, e.Action, e.Message)
+	}
+	return fmt.Sprintf("step %d (%s, instance '%s'): %s", e.Step, e.Action, e.InstanceId, e.Message)
+}
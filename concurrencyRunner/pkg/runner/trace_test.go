@@ -0,0 +1,47 @@
+package runner
+
+import (
+	"context"
+	"github.com/weinberg/concurrencyRunner/pkg/config"
+	"testing"
+	"time"
+)
+
+func TestRecordLogpointHitsOrdersByTime(t *testing.T) {
+	start := time.Now()
+	r := NewRuntime(context.Background())
+	r.InstanceAdapters["1"] = &InstanceAdapter{
+		Instance: config.Instance{Id: "1"},
+		Output: &OutputStream{hits: []LogpointHit{
+			{Location: "main.go:10", Message: "during setup", Time: start},
+			{Location: "main.go:10", Message: "between steps", Time: start.Add(2 * time.Second)},
+		}},
+	}
+	r.Trace = []TraceEntry{
+		{Step: 0, InstanceId: "1", Action: config.ActionTypeRun, Message: "first step", Time: start.Add(time.Second)},
+		{Step: 1, InstanceId: "1", Action: config.ActionTypePause, Message: "second step", Time: start.Add(3 * time.Second)},
+	}
+
+	r.recordLogpointHits()
+
+	want := []string{
+		"instance '1': logpoint at main.go:10: during setup",
+		"step 0 (run, instance '1'): first step",
+		"instance '1': logpoint at main.go:10: between steps",
+		"step 1 (pause, instance '1'): second step",
+	}
+	if len(r.Trace) != len(want) {
+		t.Fatalf("got %d entries, want %d", len(r.Trace), len(want))
+	}
+	for i, entry := range r.Trace {
+		if entry.String() != want[i] {
+			t.Errorf("entry %d: got %q, want %q", i, entry, want[i])
+		}
+	}
+
+	// hits are only recorded once
+	r.recordLogpointHits()
+	if len(r.Trace) != len(want) {
+		t.Errorf("got %d entries after a second call, want %d", len(r.Trace), len(want))
+	}
+}
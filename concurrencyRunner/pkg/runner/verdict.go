@@ -0,0 +1,119 @@
+package runner
+
+import (
+	"fmt"
+	"github.com/gookit/color"
+	"github.com/weinberg/concurrencyRunner/pkg/config"
+)
+
+// InvariantResult is the outcome of checking a single invariant
+type InvariantResult struct {
+	Invariant config.Invariant
+	Passed    bool
+	// Actual is the observed output, exit code or expression value
+	Actual  string
+	Message string
+}
+
+// ScenarioResult holds the results of a single run of a sequence
+type ScenarioResult struct {
+	Name       string
+	Invariants []InvariantResult
+	// StepErrors are errors from actions with continueOnError set. They are
+	// reported but do not fail the scenario.
+	StepErrors []*StepError
+	// Trace is the evidence recorded while running the sequence
+	Trace []TraceEntry
+	// Assertions are the results of the assert actions in the sequence
+	Assertions []AssertionResult
+	// Err is set when the scenario could not be run to completion. In
+	// explore mode the other schedules are still run.
+	Err error
+	// Deadlocked is set in explore mode when an instance timed out because
+	// the schedule keeps another instance paused while holding what it
+	// needs. Such a schedule cannot happen and is not a failure.
+	Deadlocked bool
+}
+
+// Verdict is the result of a run: one scenario in sequence mode, one per
+// schedule in explore mode
+type Verdict struct {
+	Scenarios []*ScenarioResult
+}
+
+func (s *ScenarioResult) Passed() bool {
+	if s.Err != nil {
+		return false
+	}
+	for _, result := range s.Invariants {
+		if !result.Passed {
+			return false
+		}
+	}
+	for _, result := range s.Assertions {
+		if !result.Passed {
+			return false
+		}
+	}
+	return true
+}
+
+func (v *Verdict) Passed() bool {
+	for _, scenario := range v.Scenarios {
+		if !scenario.Deadlocked && !scenario.Passed() {
+			return false
+		}
+	}
+	return true
+}
+
+// Print writes a summary of the verdict to STDOUT
+func (v *Verdict) Print() {
+	pass := color.C256(34)
+	fail := color.C256(160)
+	deadlock := color.C256(214)
+	for _, scenario := range v.Scenarios {
+		printPrefix(nil)
+		if scenario.Deadlocked {
+			deadlock.Printf("DEADLOCK")
+		} else if scenario.Passed() {
+			pass.Printf("PASS")
+		} else {
+			fail.Printf("FAIL")
+		}
+		fmt.Printf(" %s\n", scenario.Name)
+		if scenario.Deadlocked {
+			printPrefix(nil)
+			deadlock.Printf("  %s\n", scenario.Err)
+		} else if scenario.Err != nil {
+			printPrefix(nil)
+			fail.Printf("  error: %s\n", scenario.Err)
+		}
+		for _, result := range scenario.Assertions {
+			if !result.Passed {
+				printPrefix(nil)
+				fail.Printf("  failed assertion at step %d: %s\n", result.Step, result.Message)
+			}
+		}
+		for _, stepErr := range scenario.StepErrors {
+			printPrefix(nil)
+			c := color.C256(247)
+			c.Printf("  ignored error: %s\n", stepErr)
+		}
+		printTrace(scenario.Trace)
+	}
+}
+
+func printInvariantResult(result InvariantResult) {
+	printPrefix(nil)
+	c := color.C256(247)
+	status := "PASS"
+	if !result.Passed {
+		status = "FAIL"
+	}
+	c.Printf("INVARIANT %s '%s'", status, result.Invariant.Name)
+	if result.Message != "" {
+		c.Printf(": %s", result.Message)
+	}
+	c.Printf("\n")
+}
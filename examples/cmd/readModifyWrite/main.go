@@ -89,5 +89,5 @@ func main() {
 		os.Exit(1)
 	}
 
-	os.Exit(1)
+	os.Exit(0)
 }